@@ -5,11 +5,9 @@
 package test
 
 import (
-	"bytes"
 	"encoding/json"
-	"io/ioutil"
 	"path/filepath"
-	"runtime"
+	"reflect"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -24,60 +22,134 @@ type Errorf func(args ...interface{})
 //
 // The goldenFile is expected to be relative to the testdata/ folder
 // of the caller of this API.  The storage format is JSON for
-// readability of the output.
+// readability of the output.  Other formats such as Dump can be used
+// via the Format option and types can provide their own format by
+// implementing GoldenMarshaler.
+//
+// If the value is a function with no arguments, it is invoked and its
+// result is used instead.  The function can optionally return an
+// error as its second result.  Options such as Repeat can be used to
+// check that the result is deterministic before it is compared.
 //
 // If the tests are run with -golden flag, the output is not compared
 // but instead the output files are generated.
 //
+// Outputs which legitimately differ by Go version or platform can use
+// golden variants such as golden_xyz.go1.22.json or
+// golden_xyz.arm64.json which are preferred over the default golden
+// file when they match the current environment (see
+// RedundantVariants for the lookup order).  With the -golden flag,
+// the variant that would have been read is written, so a variant is
+// created by copying the default golden file to the variant name and
+// regenerating it.
+//
+// Golden files with a .gz or .zst extension are compressed
+// transparently and large outputs can be stored in a
+// content-addressed object directory via the Objects option.  Long
+// diffs are truncated with a summary of the remaining changes.
+//
 // Example Usage:
 //
-//    test.File(t.Fatal, "golden_xyz.json", func() interface{} {
+//    test.Artifact(t.Fatal, "golden_xyz.json", func() interface{} {
 //       ... do some tests and return any serializable type...
 //    })
 //
-func Artifact(errorf Errorf, outputFile string, value interface{}) {
-	var actual, expected interface{}
-
-	pc := []uintptr{0}
-	runtime.Callers(2, pc)
-	f, _ := runtime.CallersFrames(pc).Next()
+func Artifact(errorf Errorf, outputFile string, value interface{}, opts ...Option) {
+	outputFile = filepath.Join(callerTestdata(2), outputFile)
+	artifact(errorf, outputFile, value, opts...)
+}
 
-	outputFile = filepath.Join(filepath.Dir(f.File), "testdata/"+outputFile)
+func artifact(errorf Errorf, outputFile string, value interface{}, opts ...Option) {
+	o := newOptions(opts)
+	var marshaler GoldenMarshaler
+	output, ok := o.stable(errorf, func(run int) (string, bool) {
+		v, err := evaluate(value)
+		if err != nil {
+			errorf(err)
+			return "", false
+		}
 
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	enc.SetEscapeHTML(false)
-	enc.SetIndent("", "  ")
-	err := enc.Encode(value)
-	if err != nil {
-		errorf("Could not marshal value", err)
+		var bytes []byte
+		if m, ok := goldenMarshaler(v); ok {
+			marshaler = m
+			bytes, err = m.MarshalGolden()
+		} else {
+			bytes, err = o.codec.Encode(v)
+		}
+		if err != nil {
+			errorf("Could not marshal value", err)
+			return "", false
+		}
+		return string(bytes), true
+	})
+	if !ok {
 		return
 	}
-	bytes := buf.Bytes()
+	bytes := []byte(output)
 
 	if *goldenFlag {
-		if err := ioutil.WriteFile(outputFile, bytes, 0644); err != nil {
+		if err := writeGolden(outputFile, bytes, o.objectSize); err != nil {
 			errorf("Could not save golden output", outputFile, err)
 		}
 		return
 	}
 
-	if err := json.Unmarshal(bytes, &actual); err != nil {
-		errorf("Could not unmarshal value", err)
+	expected, err := readGolden(outputFile)
+	if err != nil {
+		errorf("error reading", outputFile, err)
 		return
 	}
 
-	if bytes, err = ioutil.ReadFile(outputFile); err != nil {
-		errorf("error reading", outputFile, err)
+	var diff string
+	if marshaler != nil {
+		diff, err = goldenDiff(marshaler, expected, bytes)
+	} else {
+		diff, err = o.codec.Diff(expected, bytes)
+	}
+	if verr, ok := err.(valueError); ok {
+		errorf("Could not unmarshal value", verr.error)
 		return
 	}
-
-	if err := json.Unmarshal(bytes, &expected); err != nil {
+	if err != nil {
 		errorf("could not unmarshal golden file", err)
 		return
 	}
 
-	if diff := cmp.Diff(expected, actual); diff != "" {
-		errorf("unexpected output", diff)
+	if diff != "" {
+		errorf("unexpected output", summarizeDiff(diff))
+	}
+}
+
+// evaluate invokes value if it is a function with no arguments,
+// returning its result.
+func evaluate(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Func || v.Type().NumIn() != 0 || v.Type().NumOut() == 0 {
+		return value, nil
+	}
+
+	results := v.Call(nil)
+	if len(results) > 1 {
+		if err, _ := results[1].Interface().(error); err != nil {
+			return nil, err
+		}
+	}
+	return results[0].Interface(), nil
+}
+
+// valueError is returned by jsonDiff when the actual value (rather
+// than the expected one) cannot be decoded.
+type valueError struct{ error }
+
+// jsonDiff compares two JSON documents structurally, returning an
+// empty string if they are equivalent.
+func jsonDiff(expected, actual []byte) (string, error) {
+	var e, a interface{}
+	if err := json.Unmarshal(expected, &e); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		return "", valueError{err}
 	}
+	return cmp.Diff(e, a), nil
 }
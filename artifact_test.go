@@ -78,6 +78,17 @@ func TestArtifactSuccess(t *testing.T) {
 	test.Artifact(errorf, "golden_artifact.json", nil)
 }
 
+func TestArtifactInvalidGolden(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+	test.Artifact(errorf, "input.txt", "hello")
+	if len(failed) != 2 || failed[0] != "could not unmarshal golden file" {
+		t.Error("Unexpected failure", failed)
+	}
+}
+
 func TestArtifactGoldenWriteFail(t *testing.T) {
 	defer restoreGoldenFlag()()
 
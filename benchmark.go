@@ -0,0 +1,80 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// Benchmark runs the provided function on every input matching
+// inputGlob, with one sub-benchmark per fixture.
+//
+// The inputGlob and function are the same as with Fuzz: the inputs
+// are relative to the testdata/ folder of the caller and the function
+// has one of the forms accepted by File.  The inputs are read and
+// decoded outside of the timer (a separate copy for every iteration
+// unless they are strings, so that functions may modify their input)
+// and the size of each input file is reported via b.SetBytes.
+//
+// If outputPattern is not empty, the output for each fixture is first
+// verified once against its golden file (the part of the input file
+// name matched by '*' in inputGlob is substituted into
+// outputPattern).  With the -golden flag, the golden files are
+// written instead.
+//
+// Example Usage:
+//
+//    func BenchmarkParse(b *testing.B) {
+//        test.Benchmark(b, "parse/*.in.txt", "parse/*.out.json", parse)
+//    }
+//
+func Benchmark(b *testing.B, inputGlob, outputPattern string, fn interface{}) {
+	dir := callerTestdata(2)
+
+	list, err := fixtures(dir, inputGlob, outputPattern)
+	if err != nil {
+		b.Fatal("invalid input glob", inputGlob, err)
+	}
+
+	v := reflect.ValueOf(fn)
+	for _, fx := range list {
+		if outputPattern != "" {
+			file(b.Error, fx.input, fx.output, fn)
+		}
+
+		data, err := ioutil.ReadFile(fx.input)
+		if err != nil {
+			b.Error("error reading", fx.input, err)
+			continue
+		}
+
+		arg, err := decode(v.Type().In(0), string(data))
+		if err != nil {
+			b.Error("could not decode", fx.input, err)
+			continue
+		}
+
+		// strings are immutable while other inputs are decoded
+		// for every iteration up front in case fn modifies them
+		fresh := arg.Kind() != reflect.String
+		b.Run(fx.name, func(b *testing.B) {
+			args := make([]reflect.Value, b.N)
+			for kk := range args {
+				args[kk] = arg
+				if fresh {
+					args[kk], _ = decode(v.Type().In(0), string(data))
+				}
+			}
+
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for kk := 0; kk < b.N; kk++ {
+				v.Call([]reflect.Value{args[kk]})
+			}
+		})
+	}
+}
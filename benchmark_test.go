@@ -0,0 +1,39 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func BenchmarkShout(b *testing.B) {
+	test.Benchmark(b, "cases/*.in.txt", "cases/*.out.txt", shout)
+}
+
+func BenchmarkShoutUnverified(b *testing.B) {
+	test.Benchmark(b, "cases/*.in.txt", "", shout)
+}
+
+func BenchmarkShoutJSON(b *testing.B) {
+	test.Benchmark(b, "in*.json", "out*.json", func(input struct{ OK int }) struct{ OK int } {
+		return input
+	})
+}
+
+func BenchmarkMutatingInput(b *testing.B) {
+	corrupted := false
+	test.Benchmark(b, "cases/*.in.txt", "", func(input []byte) []byte {
+		corrupted = corrupted || len(input) > 0 && input[0] == 0
+		if len(input) > 0 {
+			input[0] = 0
+		}
+		return input
+	})
+	if corrupted {
+		b.Error("input was modified by an earlier iteration")
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Calls implements recording the calls made on a dependency so that
+// the interaction can be compared against a golden file.
+//
+// Go reflection cannot implement interfaces, so the calls are routed
+// through a thin wrapper type that calls Call for each method.
+// Dependencies which are functions can be wrapped directly via Func.
+// Wrappers for interfaces can be generated with cmd/testfake.
+//
+// Errors are recorded as their messages.
+//
+// A recorded log can also be replayed in place of the dependency
+// via Load and Replay.
+//
+// Example Usage:
+//
+//    type recordingStore struct{ calls *test.Calls }
+//
+//    func (r recordingStore) Get(key string) (string, error) {
+//        results := r.calls.Call("Get", key)
+//        err, _ := results[1].(error)
+//        return results[0].(string), err
+//    }
+//
+//    calls := &test.Calls{Target: store}
+//    doSomething(recordingStore{calls})
+//    calls.Check(t.Error, "store_calls.json")
+//
+type Calls struct {
+	// Target is the value whose methods are invoked by Call.
+	Target interface{}
+
+	// IgnoreArgs excludes the arguments from the log.
+	IgnoreArgs bool
+
+	// Unordered compares the log without regard to the order of
+	// the calls.
+	Unordered bool
+
+	mu       sync.Mutex
+	log      []Call
+	recorded []Call
+	used     []bool
+	errorf   Errorf
+}
+
+// Call is a single entry of the call log.
+type Call struct {
+	Method  string        `json:"method"`
+	Args    []interface{} `json:"args,omitempty"`
+	Results []interface{} `json:"results,omitempty"`
+}
+
+// Call invokes the named method of Target with the provided args,
+// logs the call and returns the results.  For variadic methods, the
+// variadic arguments can be passed either individually or as a
+// single slice.
+func (c *Calls) Call(method string, args ...interface{}) []interface{} {
+	m := reflect.ValueOf(c.Target).MethodByName(method)
+	if !m.IsValid() {
+		panic(fmt.Sprintf("test: %T has no method %s", c.Target, method))
+	}
+	return c.invoke(method, m, args)
+}
+
+// Func wraps the function fn so that calls to it are logged under
+// the provided name.  The result has the same type as fn.
+func (c *Calls) Func(name string, fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	wrapped := reflect.MakeFunc(v.Type(), func(in []reflect.Value) []reflect.Value {
+		args := make([]interface{}, len(in))
+		for idx, arg := range in {
+			args[idx] = arg.Interface()
+		}
+		results := c.invoke(name, v, args)
+		out := make([]reflect.Value, len(results))
+		for idx, r := range results {
+			out[idx] = value(r, v.Type().Out(idx))
+		}
+		return out
+	})
+	return wrapped.Interface()
+}
+
+// Log returns a copy of the calls logged so far.
+func (c *Calls) Log() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.log...)
+}
+
+// Check compares the call log against the golden file (relative to
+// the testdata/ folder of the caller) as with Artifact.
+func (c *Calls) Check(errorf Errorf, goldenFile string) {
+	log := c.Log()
+	if log == nil {
+		log = []Call{}
+	}
+
+	if c.Unordered {
+		keys := make([]string, len(log))
+		for idx, call := range log {
+			bytes, _ := json.Marshal(call)
+			keys[idx] = string(bytes)
+		}
+		sort.Sort(byKey{keys, log})
+	}
+
+	artifact(errorf, filepath.Join(callerTestdata(2), goldenFile), log)
+}
+
+func (c *Calls) invoke(name string, fn reflect.Value, args []interface{}) []interface{} {
+	t := fn.Type()
+	in := make([]reflect.Value, len(args))
+	for idx, arg := range args {
+		in[idx] = value(arg, paramType(t, idx))
+	}
+
+	var out []reflect.Value
+	if t.IsVariadic() && len(args) == t.NumIn() && in[len(in)-1].Type().AssignableTo(t.In(t.NumIn()-1)) {
+		out = fn.CallSlice(in)
+	} else {
+		out = fn.Call(in)
+	}
+
+	results := make([]interface{}, len(out))
+	for idx, r := range out {
+		results[idx] = r.Interface()
+	}
+
+	call := Call{Method: name, Results: loggable(results)}
+	if !c.IgnoreArgs {
+		call.Args = loggable(args)
+	}
+
+	c.mu.Lock()
+	c.log = append(c.log, call)
+	c.mu.Unlock()
+
+	return results
+}
+
+// paramType returns the type of the idx'th argument, accounting for
+// variadic functions.
+func paramType(t reflect.Type, idx int) reflect.Type {
+	if t.IsVariadic() && idx >= t.NumIn()-1 {
+		return t.In(t.NumIn() - 1).Elem()
+	}
+	return t.In(idx)
+}
+
+// value converts v to a reflect.Value of type t, using the zero value
+// for nil.
+func value(v interface{}, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.Type().AssignableTo(t) && rv.Type().ConvertibleTo(t) {
+		rv = rv.Convert(t)
+	}
+	return rv
+}
+
+// loggable replaces errors with their messages.
+func loggable(values []interface{}) []interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]interface{}, len(values))
+	for idx, v := range values {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		result[idx] = v
+	}
+	return result
+}
+
+// Load reads a call log recorded via Check from the golden file
+// (relative to the testdata/ folder of the caller) for use with
+// Replay.  Later mismatches are reported via errorf.
+func (c *Calls) Load(errorf Errorf, goldenFile string) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errorf = errorf
+	bytes, err := readGolden(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	if err := json.Unmarshal(bytes, &c.recorded); err != nil {
+		errorf("could not unmarshal golden file", err)
+		return
+	}
+	c.used = make([]bool, len(c.recorded))
+}
+
+// Replay serves a call from the log read via Load instead of calling
+// Target.  The next recorded call (or, if Unordered, the first unused
+// call of the method with the same args) must match the method and
+// args.  Its recorded results are decoded into the results, which
+// must be pointers.  Errors are recreated from their messages.
+//
+// Mismatches are reported via the errorf provided to Load and leave
+// the results untouched.
+func (c *Calls) Replay(method string, args []interface{}, results ...interface{}) {
+	call := Call{Method: method}
+	if !c.IgnoreArgs {
+		call.Args = loggable(args)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.errorf == nil {
+		panic("test: Replay called without Load")
+	}
+
+	expected, diff := c.next(call)
+	if expected == nil {
+		c.errorf("unexpected call", method, diff)
+		return
+	}
+
+	for idx, r := range results {
+		if idx >= len(expected.Results) {
+			break
+		}
+		if err := decodeResult(expected.Results[idx], r); err != nil {
+			c.errorf("could not decode result", method, idx, err)
+		}
+	}
+	call.Results = expected.Results
+	c.log = append(c.log, call)
+}
+
+// next finds the recorded call matching call, returning the diff
+// against the expected call on failure.
+func (c *Calls) next(call Call) (*Call, string) {
+	actual, _ := json.Marshal(call)
+	diff := "no more recorded calls"
+	for idx := range c.recorded {
+		if c.used[idx] {
+			continue
+		}
+
+		expected := Call{Method: c.recorded[idx].Method, Args: c.recorded[idx].Args}
+		bytes, _ := json.Marshal(expected)
+		if diff, _ = jsonDiff(bytes, actual); diff == "" {
+			c.used[idx] = true
+			return &c.recorded[idx], ""
+		}
+		if !c.Unordered {
+			break
+		}
+	}
+	return nil, diff
+}
+
+// decodeResult stores the recorded value into the pointer r.
+func decodeResult(recorded, r interface{}) error {
+	if errp, ok := r.(*error); ok {
+		*errp = nil
+		if recorded != nil {
+			*errp = errors.New(fmt.Sprint(recorded))
+		}
+		return nil
+	}
+
+	bytes, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+type byKey struct {
+	keys []string
+	log  []Call
+}
+
+func (b byKey) Len() int {
+	return len(b.keys)
+}
+
+func (b byKey) Less(i, j int) bool {
+	return b.keys[i] < b.keys[j]
+}
+
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.log[i], b.log[j] = b.log[j], b.log[i]
+}
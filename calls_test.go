@@ -0,0 +1,139 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+type store interface {
+	Get(key string) (string, error)
+	Put(key, value string) error
+	Keys(prefix string, limit ...int) []string
+}
+
+type mapStore map[string]string
+
+func (m mapStore) Get(key string) (string, error) {
+	if v, ok := m[key]; ok {
+		return v, nil
+	}
+	return "", errors.New("not found: " + key)
+}
+
+func (m mapStore) Put(key, value string) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapStore) Keys(prefix string, limit ...int) []string {
+	var keys []string
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(limit) > 0 && len(keys) > limit[0] {
+		keys = keys[:limit[0]]
+	}
+	return keys
+}
+
+type recordingStore struct {
+	calls *test.Calls
+}
+
+func (r recordingStore) Get(key string) (string, error) {
+	results := r.calls.Call("Get", key)
+	err, _ := results[1].(error)
+	return results[0].(string), err
+}
+
+func (r recordingStore) Put(key, value string) error {
+	err, _ := r.calls.Call("Put", key, value)[0].(error)
+	return err
+}
+
+func (r recordingStore) Keys(prefix string, limit ...int) []string {
+	return r.calls.Call("Keys", prefix, limit)[0].([]string)
+}
+
+func useStore(s store) {
+	_ = s.Put("a/1", "one")
+	_ = s.Put("a/2", "two")
+	_, _ = s.Get("a/1")
+	_, _ = s.Get("b")
+	s.Keys("a/", 1)
+}
+
+func TestCalls(t *testing.T) {
+	calls := &test.Calls{Target: mapStore{}}
+	useStore(recordingStore{calls})
+	calls.Check(t.Error, "calls_store.json")
+
+	if log := calls.Log(); len(log) != 5 || log[3].Results[1] != "not found: b" {
+		t.Error("Unexpected log", log)
+	}
+}
+
+func TestCallsUnorderedIgnoreArgs(t *testing.T) {
+	calls := &test.Calls{Target: mapStore{}, IgnoreArgs: true, Unordered: true}
+	s := recordingStore{calls}
+	s.Keys("a/")
+	_, _ = s.Get("b")
+	_ = s.Put("x", "y")
+	calls.Check(t.Error, "calls_unordered.json")
+
+	calls = &test.Calls{Target: mapStore{}, IgnoreArgs: true, Unordered: true}
+	s = recordingStore{calls}
+	_ = s.Put("z", "w")
+	_, _ = s.Get("b")
+	s.Keys("q/", 5)
+	calls.Check(t.Error, "calls_unordered.json")
+}
+
+func TestCallsFunc(t *testing.T) {
+	calls := &test.Calls{}
+	var total int64
+	add := calls.Func("add", func(delta int64, labels ...string) error {
+		total += delta
+		if total > 5 {
+			return errors.New("overflow")
+		}
+		return nil
+	}).(func(int64, ...string) error)
+
+	if err := add(2, "x", "y"); err != nil {
+		t.Error("Unexpected error", err)
+	}
+	if err := add(4); err == nil {
+		t.Error("Unexpected success")
+	}
+	calls.Check(t.Error, "calls_func.json")
+}
+
+func TestCallsMismatch(t *testing.T) {
+	calls := &test.Calls{Target: mapStore{}}
+	_ = recordingStore{calls}.Put("a/1", "uno")
+
+	failed := false
+	calls.Check(func(args ...interface{}) { failed = true }, "calls_store.json")
+	if !failed {
+		t.Error("Failed to fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Unexpected success for missing method")
+		}
+	}()
+	calls.Call("Missing")
+}
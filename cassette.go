@@ -0,0 +1,311 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Cassette implements recording and replaying HTTP interactions.
+//
+// The http.RoundTripper returned by RoundTripper replays the
+// interactions stored in a cassette file.  If the tests are run with
+// -golden flag, requests are instead passed to Transport and the
+// interactions are recorded into the cassette file.
+//
+// Requests are matched by method, path, query and body by default, so
+// that cassettes recorded against servers on random ports (such as
+// httptest.Server) can be replayed.
+//
+// Example Usage:
+//
+//    transport := test.Cassette{Redact: []string{"Authorization"}}.
+//        RoundTripper(t.Error, "github.cassette.json")
+//    defer transport.(io.Closer).Close()
+//    client := &http.Client{Transport: transport}
+//
+type Cassette struct {
+	// Transport is used to make the actual requests when
+	// recording. It defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Match reports whether a recorded request matches the
+	// actual request.  It defaults to comparing the method, the
+	// path and query of the URL and the body.
+	Match func(actual, recorded RecordedRequest) bool
+
+	// MatchHost also compares the scheme and host (including the
+	// port) of the URL with the default matcher.
+	MatchHost bool
+
+	// Redact lists the headers whose values are replaced with
+	// REDACTED in the cassette.
+	Redact []string
+
+	// RedactBody, if not nil, transforms the request and response
+	// bodies before they are recorded or matched.
+	RedactBody func(body []byte) []byte
+}
+
+// Interaction is a request and its response as stored in a cassette.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as stored in a cassette.
+type RecordedRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   RecordedBody `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as stored in a cassette.
+type RecordedResponse struct {
+	Status int          `json:"status"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   RecordedBody `json:"body,omitempty"`
+}
+
+// RecordedBody is a request or response body.  It is stored as a
+// JSON string if it is valid UTF-8 and as a base64 encoded string
+// prefixed with "base64:" otherwise.
+type RecordedBody []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b RecordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) && !strings.HasPrefix(string(b), "base64:") {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal("base64:" + base64.StdEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *RecordedBody) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(s, "base64:") {
+		*b = RecordedBody(s)
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s[len("base64:"):])
+	*b = decoded
+	return err
+}
+
+// RoundTripper returns a transport which replays (or records) the
+// interactions in the cassette file, relative to the testdata/
+// folder of the caller.  Requests which do not match any unused
+// recorded interaction are reported via errorf and fail.
+//
+// The transport implements io.Closer.  Close reports the recorded
+// interactions which were not replayed.  When recording, the cassette
+// file is written as requests are made and by Close, so that a test
+// which no longer makes any requests leaves an empty cassette.
+func (c Cassette) RoundTripper(errorf Errorf, cassetteFile string) http.RoundTripper {
+	rt := &cassetteTransport{c: c, errorf: errorf}
+	rt.file = filepath.Join(callerTestdata(2), cassetteFile)
+	if *goldenFlag {
+		return rt
+	}
+
+	data, err := ioutil.ReadFile(rt.file)
+	if err != nil {
+		errorf("error reading", rt.file, err)
+		return rt
+	}
+	if err := json.Unmarshal(data, &rt.interactions); err != nil {
+		errorf("could not unmarshal cassette", rt.file, err)
+	}
+	rt.used = make([]bool, len(rt.interactions))
+	return rt
+}
+
+type cassetteTransport struct {
+	c      Cassette
+	errorf Errorf
+	file   string
+
+	sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+func (rt *cassetteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	req := RecordedRequest{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Header: rt.redact(r.Header),
+		Body:   rt.redactBody(body),
+	}
+
+	if *goldenFlag {
+		return rt.record(r, req)
+	}
+
+	rt.Lock()
+	defer rt.Unlock()
+
+	for idx, interaction := range rt.interactions {
+		if !rt.used[idx] && rt.match(req, interaction.Request) {
+			rt.used[idx] = true
+			return interaction.Response.response(r), nil
+		}
+	}
+
+	rt.errorf("unmatched request", r.Method, r.URL.String())
+	return nil, errors.New("test: no recorded interaction for " + r.Method + " " + r.URL.String())
+}
+
+// Close reports the recorded interactions which were not replayed.
+// When recording, it saves the cassette even if no requests were
+// made.
+func (rt *cassetteTransport) Close() error {
+	rt.Lock()
+	defer rt.Unlock()
+
+	if *goldenFlag {
+		rt.save()
+		return nil
+	}
+
+	for idx, interaction := range rt.interactions {
+		if !rt.used[idx] {
+			rt.errorf("unused interaction", interaction.Request.Method, interaction.Request.URL)
+		}
+	}
+	return nil
+}
+
+func (rt *cassetteTransport) record(r *http.Request, req RecordedRequest) (*http.Response, error) {
+	transport := rt.c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	rt.Lock()
+	defer rt.Unlock()
+
+	rt.interactions = append(rt.interactions, Interaction{
+		Request: req,
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: rt.redact(resp.Header),
+			Body:   rt.redactBody(body),
+		},
+	})
+
+	rt.save()
+	return resp, nil
+}
+
+// save writes all the recorded interactions to the cassette file.
+func (rt *cassetteTransport) save() {
+	interactions := rt.interactions
+	if interactions == nil {
+		interactions = []Interaction{}
+	}
+	data, err := json.MarshalIndent(interactions, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(rt.file, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		rt.errorf("Could not save golden output", rt.file, err)
+	}
+}
+
+func (rt *cassetteTransport) match(actual, recorded RecordedRequest) bool {
+	if rt.c.Match != nil {
+		return rt.c.Match(actual, recorded)
+	}
+	return actual.Method == recorded.Method &&
+		rt.matchURL(actual.URL, recorded.URL) &&
+		bytes.Equal(actual.Body, recorded.Body)
+}
+
+func (rt *cassetteTransport) matchURL(actual, recorded string) bool {
+	if rt.c.MatchHost {
+		return actual == recorded
+	}
+	a, err1 := url.Parse(actual)
+	r, err2 := url.Parse(recorded)
+	if err1 != nil || err2 != nil {
+		return actual == recorded
+	}
+	return a.Path == r.Path && a.Query().Encode() == r.Query().Encode()
+}
+
+func (rt *cassetteTransport) redact(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	result := h.Clone()
+	for _, name := range rt.c.Redact {
+		if _, ok := result[http.CanonicalHeaderKey(name)]; ok {
+			result.Set(name, "REDACTED")
+		}
+	}
+	return result
+}
+
+func (rt *cassetteTransport) redactBody(body []byte) RecordedBody {
+	if rt.c.RedactBody != nil {
+		body = rt.c.RedactBody(body)
+	}
+	return body
+}
+
+func (rr RecordedResponse) response(r *http.Request) *http.Response {
+	header := rr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.Status, http.StatusText(rr.Status)),
+		StatusCode:    rr.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       r,
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, errors.New("unexpected network access")
+}
+
+func fetch(t *testing.T, client *http.Client, method, url, body string) string {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	check(err)
+	req.Header.Set("Authorization", "secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	check(err)
+	return fmt.Sprint(resp.StatusCode, " ", string(data))
+}
+
+func TestCassette(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	_, fname, _, _ := runtime.Caller(0)
+	golden := path.Join(path.Dir(fname), "testdata/golden_cassette.json")
+	defer os.Remove(golden)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/binary" {
+			_, _ = w.Write([]byte{0xff, 0xfe, 0})
+			return
+		}
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.Path, body, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	cassette := test.Cassette{Redact: []string{"Authorization"}}
+
+	check(flag.Set("golden", "true"))
+	client := &http.Client{Transport: cassette.RoundTripper(t.Error, "golden_cassette.json")}
+	recorded := []string{
+		fetch(t, client, "GET", server.URL+"/a", ""),
+		fetch(t, client, "POST", server.URL+"/b", "hello"),
+		fetch(t, client, "GET", server.URL+"/binary", ""),
+	}
+
+	data, err := ioutil.ReadFile(golden)
+	check(err)
+	if bytes.Contains(data, []byte("\"secret\"")) || !bytes.Contains(data, []byte("base64:")) {
+		t.Error("Unexpected cassette", string(data))
+	}
+
+	// creating a recording transport does not truncate the cassette
+	// but closing it without any requests does
+	recording := cassette.RoundTripper(t.Error, "golden_cassette.json")
+	if after, err := ioutil.ReadFile(golden); err != nil || !bytes.Equal(after, data) {
+		t.Error("Unexpected cassette", string(after), err)
+	}
+	check(recording.(io.Closer).Close())
+	if after, err := ioutil.ReadFile(golden); err != nil || string(after) != "[]\n" {
+		t.Error("Unexpected cassette", string(after), err)
+	}
+	check(ioutil.WriteFile(golden, data, 0644))
+
+	check(flag.Set("golden", "false"))
+	cassette.Transport = failingTransport{}
+	transport := cassette.RoundTripper(t.Error, "golden_cassette.json")
+	client = &http.Client{Transport: transport}
+	replayed := []string{
+		fetch(t, client, "GET", server.URL+"/a", ""),
+		fetch(t, client, "POST", server.URL+"/b", "hello"),
+		fetch(t, client, "GET", server.URL+"/binary", ""),
+	}
+	if strings.Join(recorded, "\n") != strings.Join(replayed, "\n") {
+		t.Error("Unexpected replay", recorded, replayed)
+	}
+	check(transport.(io.Closer).Close())
+
+	// the host is ignored by default
+	other := "http://localhost:1"
+	client = &http.Client{Transport: cassette.RoundTripper(t.Error, "golden_cassette.json")}
+	if fetch(t, client, "GET", other+"/a", "") != recorded[0] {
+		t.Error("Unexpected mismatch")
+	}
+
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+	client = &http.Client{Transport: cassette.RoundTripper(errorf, "golden_cassette.json")}
+	fetch(t, client, "GET", server.URL+"/a", "")
+	fetch(t, client, "GET", server.URL+"/a", "")
+	fetch(t, client, "POST", server.URL+"/b", "goodbye")
+	if len(reports) != 2 || reports[0] != "unmatched request" {
+		t.Error("Unexpected reports", reports)
+	}
+
+	reports = nil
+	hostCassette := cassette
+	hostCassette.MatchHost = true
+	transport = hostCassette.RoundTripper(errorf, "golden_cassette.json")
+	client = &http.Client{Transport: transport}
+	fetch(t, client, "GET", other+"/a", "")
+	fetch(t, client, "GET", server.URL+"/a", "")
+	check(transport.(io.Closer).Close())
+	expected := []interface{}{"unmatched request", "unused interaction", "unused interaction"}
+	if fmt.Sprint(reports) != fmt.Sprint(expected) {
+		t.Error("Unexpected reports", reports)
+	}
+
+	// custom matchers can ignore the body
+	cassette.Match = func(actual, recorded test.RecordedRequest) bool {
+		return actual.Method == recorded.Method && actual.URL == recorded.URL
+	}
+	client = &http.Client{Transport: cassette.RoundTripper(t.Error, "golden_cassette.json")}
+	if fetch(t, client, "POST", server.URL+"/b", "goodbye") != recorded[1] {
+		t.Error("Unexpected mismatch")
+	}
+}
+
+func TestCassetteMissing(t *testing.T) {
+	failed := false
+	errorf := func(args ...interface{}) {
+		failed = true
+	}
+
+	test.Cassette{}.RoundTripper(errorf, "non-existent")
+	if !failed {
+		t.Error("Failed to fail")
+	}
+}
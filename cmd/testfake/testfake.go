@@ -0,0 +1,47 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Command testfake generates typed recorders and fakes for Go
+// interfaces
+//
+//    $ go get github.com/tvastar/test/cmd/testfake
+//
+// The interface is loaded by import path and name.  For an interface
+// named Store, two types are generated: StoreRecorder which forwards
+// calls to the real implementation while recording them via
+// test.Calls, and StoreFake which replays the calls from a golden
+// file loaded via test.Calls.Load.
+//
+// Usage:
+//
+//    $ testfake -o store_fake_test.go -pkg store_test example.com/store Store
+//
+// This is typically used with go:generate:
+//
+//    //go:generate go run github.com/tvastar/test/cmd/testfake -o store_fake_test.go -pkg store_test example.com/store Store
+//
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/tvastar/test"
+)
+
+var output = flag.String("o", "", "output file name")
+var pkg = flag.String("pkg", "", "package name of the output file")
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 2 || *output == "" || *pkg == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if err := test.Fake(flag.Arg(0), flag.Arg(1), *output, *pkg); err != nil {
+		log.Fatal(err)
+	}
+}
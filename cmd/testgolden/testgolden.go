@@ -0,0 +1,66 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Command testgolden removes Go version and platform specific golden
+// file variants which have become identical to the default golden
+// file along with objects no longer referenced by any golden file.
+//
+//    $ go get github.com/tvastar/test/cmd/testgolden
+//
+// The directories default to testdata and are searched recursively.
+// The removed files are printed.  With -n, the files are only
+// printed.
+//
+// Usage:
+//
+//    $ testgolden [-n] [dir ...]
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tvastar/test"
+)
+
+var dryRun = flag.Bool("n", false, "print the files without removing them")
+
+func main() {
+	flag.Parse()
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"testdata"}
+	}
+
+	for _, dir := range dirs {
+		variants, err := test.RedundantVariants(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// the variants do not keep objects in use as they are
+		// removed (even with -n)
+		objects, err := test.UnusedObjects(dir, variants...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		remove(variants)
+		remove(objects)
+	}
+}
+
+func remove(names []string) {
+	for _, name := range names {
+		fmt.Println(name)
+		if *dryRun {
+			continue
+		}
+		if err := os.Remove(name); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Codec is the storage format of golden files produced by Artifact.
+//
+// Encode must be deterministic.  Diff compares two encoded values,
+// returning an empty string if they are equivalent.
+type Codec interface {
+	Encode(value interface{}) ([]byte, error)
+	Diff(expected, actual []byte) (string, error)
+}
+
+// JSON is the default Codec.  Values are stored as indented JSON and
+// compared structurally.
+var JSON Codec = jsonCodec{}
+
+// Format sets the Codec used by Artifact.  It defaults to JSON.
+//
+// Example Usage:
+//
+//    test.Artifact(t.Error, "state.dump", value, test.Format(test.Dump))
+//
+func Format(c Codec) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(value interface{}) ([]byte, error) {
+	value, err := goldenJSON(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (jsonCodec) Diff(expected, actual []byte) (string, error) {
+	return jsonDiff(expected, actual)
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+)
+
+// Differential implements testing two implementations against each
+// other over a set of fixtures.
+//
+// The inputGlob is relative to the testdata/ folder of the caller
+// and both functions must be of one of the forms accepted by File.
+// Each input is passed through both functions and any disagreement
+// (including one of them failing) is reported along with the name of
+// the fixture.  If both outputs are of types other than string,
+// []byte, []rune or GoldenMarshaler, they are compared structurally
+// (as with Artifact) and otherwise line by line (as with File).
+//
+// If outputPattern is not empty, the outputs of both functions are
+// also compared against the golden file of each fixture (the part of
+// the input file name matched by '*' in inputGlob is substituted into
+// outputPattern).  If the tests are run with -golden flag, the golden
+// files are instead written using the output of the first (reference)
+// function.
+//
+// Example Usage:
+//
+//    test.Differential(t.Error, "parse/*.in.txt", "", parseOld, parseNew)
+//
+func Differential(errorf Errorf, inputGlob, outputPattern string, fn1, fn2 interface{}) {
+	dir := callerTestdata(2)
+
+	list, err := fixtures(dir, inputGlob, outputPattern)
+	if err != nil {
+		errorf("invalid input glob", inputGlob, err)
+		return
+	}
+
+	structured := isStructured(reflect.TypeOf(fn1).Out(0)) && isStructured(reflect.TypeOf(fn2).Out(0))
+	for _, fx := range list {
+		bytes, err := ioutil.ReadFile(fx.input)
+		if err != nil {
+			errorf("error reading", fx.input, err)
+			continue
+		}
+
+		out1, err1 := invoke(fn1, string(bytes))
+		out2, err2 := invoke(fn2, string(bytes))
+		switch {
+		case err1 != nil && err2 != nil:
+			if err1.Error() != err2.Error() {
+				errorf(fx.name+": implementations fail differently", linediff(err1.Error(), err2.Error()))
+			}
+			continue
+		case err1 != nil || err2 != nil:
+			errorf(fx.name+": implementations disagree", fmt.Sprint("first: ", err1, ", second: ", err2))
+			continue
+		}
+
+		if diff, err := outputDiff(out1, out2, structured); err != nil || diff != "" {
+			errorf(fx.name+": implementations disagree", diff, err)
+		}
+
+		if outputPattern == "" {
+			continue
+		}
+
+		if *goldenFlag {
+			if err := writeGolden(fx.output, []byte(out1), 0); err != nil {
+				errorf("Could not save golden output", fx.output, err)
+			}
+			continue
+		}
+
+		golden, err := readGolden(fx.output)
+		if err != nil {
+			errorf("error reading", fx.output, err)
+			continue
+		}
+
+		for idx, out := range []string{out1, out2} {
+			if diff, err := outputDiff(string(golden), out, structured); err != nil || diff != "" {
+				errorf(fmt.Sprintf("%s: implementation %d differs from golden", fx.name, idx+1), diff, err)
+			}
+		}
+	}
+}
+
+// isStructured reports whether outputs of type t are encoded as JSON.
+func isStructured(t reflect.Type) bool {
+	return !isText(t) && !t.Implements(goldenMarshalerType)
+}
+
+func outputDiff(expected, actual string, structured bool) (string, error) {
+	if structured {
+		return jsonDiff([]byte(expected), []byte(actual))
+	}
+	if expected == actual {
+		return "", nil
+	}
+	return linediff(expected, actual), nil
+}
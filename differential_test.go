@@ -0,0 +1,109 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func TestDifferentialSuccess(t *testing.T) {
+	shout2 := func(input []byte) ([]byte, error) {
+		return []byte(strings.ToUpper(string(input))), nil
+	}
+	test.Differential(t.Error, "cases/*.in.txt", "", shout, shout2)
+	test.Differential(t.Error, "cases/*.in.txt", "cases/*.out.txt", shout, shout2)
+
+	type ok struct{ OK int }
+	id1 := func(input ok) ok { return input }
+	id2 := func(input map[string]int) map[string]int { return input }
+	test.Differential(t.Error, "in*.json", "out*.json", id1, id2)
+}
+
+func TestDifferentialDisagree(t *testing.T) {
+	var reports []string
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0].(string))
+	}
+
+	shout2 := func(input string) string {
+		if strings.HasPrefix(input, "hello") {
+			return "HELLO"
+		}
+		return strings.ToUpper(input)
+	}
+	test.Differential(errorf, "cases/*.in.txt", "cases/*.out.txt", shout, shout2)
+
+	expected := []string{
+		"hello: implementations disagree",
+		"hello: implementation 2 differs from golden",
+	}
+	if strings.Join(reports, "\n") != strings.Join(expected, "\n") {
+		t.Error("Unexpected reports", reports)
+	}
+}
+
+func TestDifferentialErrors(t *testing.T) {
+	var reports []string
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0].(string))
+	}
+
+	fail2 := func(input string) (string, error) {
+		return "", errors.New("failure 2")
+	}
+	test.Differential(errorf, "cases/hello.in.txt", "", fail, fail)
+	test.Differential(errorf, "cases/hello.in.txt", "", fail, fail2)
+	test.Differential(errorf, "cases/hello.in.txt", "", fail, identity)
+	test.Differential(errorf, "cases/[", "", fail, identity)
+	test.Differential(errorf, "cases/*.in.txt", "non-existent/*", shout, shout)
+
+	expected := []string{
+		"cases/hello.in.txt: implementations fail differently",
+		"cases/hello.in.txt: implementations disagree",
+		"invalid input glob",
+		"error reading",
+		"error reading",
+	}
+	if strings.Join(reports, "\n") != strings.Join(expected, "\n") {
+		t.Error("Unexpected reports", reports)
+	}
+}
+
+func TestDifferentialGolden(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	_, fname, _, _ := runtime.Caller(0)
+	defer os.Remove(path.Join(path.Dir(fname), "testdata/golden_differential_hello.txt"))
+	defer os.Remove(path.Join(path.Dir(fname), "testdata/golden_differential_fox.txt"))
+
+	check(flag.Set("golden", "true"))
+	test.Differential(t.Error, "cases/*.in.txt", "golden_differential_*.txt", shout, shout)
+	check(flag.Set("golden", "false"))
+	test.Differential(t.Error, "cases/*.in.txt", "golden_differential_*.txt", shout, shout)
+}
+
+func TestDifferentialGoldenMarshaler(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer os.Remove("testdata/golden_differential_grid_hello.txt")
+	defer os.Remove("testdata/golden_differential_grid_fox.txt")
+
+	toGrid := func(s string) grid { return grid{{len(s)}} }
+	toText := func(s string) string { return fmt.Sprintln(len(s)) }
+
+	setGoldenFlag(true)
+	test.Differential(t.Error, "cases/*.in.txt", "golden_differential_grid_*.txt", toGrid, toText)
+	setGoldenFlag(false)
+	test.Differential(t.Error, "cases/*.in.txt", "golden_differential_grid_*.txt", toGrid, toText)
+	test.Differential(t.Error, "cases/*.in.txt", "golden_differential_grid_*.txt", toText, toGrid)
+}
@@ -0,0 +1,305 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Dump is a Codec which renders any Go value deterministically in a
+// Go-like syntax.  Unlike JSON, it includes unexported struct fields,
+// renders errors as their type and message along with the errors
+// they wrap, renders NaN and infinities, describes channels and
+// functions by their type and renders pointers that were already
+// visited as back-references to the path where they were first seen:
+//
+//    &test_test.node{
+//      Name: "root",
+//      next: &test_test.node{
+//        Name: "child",
+//        next: <ref $>,
+//      },
+//      err: *fmt.wrapError("load: EOF" wraps *errors.errorString("EOF")),
+//    }
+//
+// Map entries are sorted by the rendering of their keys,
+// time.Time values are rendered in RFC 3339 format and values which
+// implement GoldenMarshaler are rendered as their golden form.  Encoded values
+// are compared line by line.
+var Dump Codec = dumpCodec{}
+
+type dumpCodec struct{}
+
+func (dumpCodec) Encode(value interface{}) ([]byte, error) {
+	d := &dumper{seen: map[dumpRef]string{}}
+	if value == nil {
+		d.buf.WriteString("nil")
+	} else {
+		v := reflect.New(reflect.TypeOf(value)).Elem()
+		v.Set(reflect.ValueOf(value))
+		d.dump(v, "$", "")
+	}
+	d.buf.WriteString("\n")
+	return d.buf.Bytes(), d.err
+}
+
+func (dumpCodec) Diff(expected, actual []byte) (string, error) {
+	if bytes.Equal(expected, actual) {
+		return "", nil
+	}
+	return linediff(string(expected), string(actual)), nil
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	timeType  = reflect.TypeOf(time.Time{})
+)
+
+type dumper struct {
+	buf  bytes.Buffer
+	seen map[dumpRef]string
+	err  error
+}
+
+// dumpRef identifies a pointer, map or slice.  The type and length
+// distinguish a struct from its first field and a slice from its
+// prefixes.
+type dumpRef struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func (d *dumper) dump(v reflect.Value, path, indent string) {
+	v = exposed(v)
+
+	if v.CanInterface() {
+		if m, ok := goldenMarshaler(v.Interface()); ok {
+			data, err := m.MarshalGolden()
+			if err != nil && d.err == nil {
+				d.err = err
+			}
+			d.buf.WriteString(v.Type().String() + "(golden " + strconv.Quote(string(data)) + ")")
+			return
+		}
+	}
+
+	if v.CanInterface() && v.Kind() != reflect.Interface && v.Type().Implements(errorType) && !isNilValue(v) {
+		d.buf.WriteString(dumpError(v.Interface().(error)))
+		return
+	}
+	if v.CanInterface() && v.Type() == timeType {
+		d.buf.WriteString("time.Time(" + strconv.Quote(v.Interface().(time.Time).Format(time.RFC3339Nano)) + ")")
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		d.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		d.buf.WriteString(formatFloat(v.Float(), v.Type().Bits()))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		bits := v.Type().Bits() / 2
+		d.buf.WriteString("complex(" + formatFloat(real(c), bits) + ", " + formatFloat(imag(c), bits) + ")")
+	case reflect.String:
+		d.buf.WriteString(strconv.Quote(v.String()))
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		d.buf.WriteString(v.Type().String())
+		if v.IsNil() {
+			d.buf.WriteString("(nil)")
+		} else if v.Kind() == reflect.Chan {
+			fmt.Fprintf(&d.buf, "{len: %d, cap: %d}", v.Len(), v.Cap())
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			d.buf.WriteString("nil")
+			return
+		}
+		d.dump(v.Elem(), path, indent)
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.buf.WriteString(v.Type().String() + "(nil)")
+			return
+		}
+		if v.Type().Elem().Size() > 0 && d.visit(v, path) {
+			return
+		}
+		d.buf.WriteString("&")
+		d.dump(v.Elem(), "(*"+path+")", indent)
+	case reflect.Struct:
+		d.buf.WriteString(v.Type().String() + "{")
+		if v.NumField() > 0 {
+			d.buf.WriteString("\n")
+		}
+		for kk := 0; kk < v.NumField(); kk++ {
+			name := v.Type().Field(kk).Name
+			d.buf.WriteString(indent + "  " + name + ": ")
+			d.dump(v.Field(kk), path+"."+name, indent+"  ")
+			d.buf.WriteString(",\n")
+		}
+		if v.NumField() > 0 {
+			d.buf.WriteString(indent)
+		}
+		d.buf.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			d.buf.WriteString(v.Type().String() + "(nil)")
+			return
+		}
+		if v.Kind() == reflect.Slice && v.Len() > 0 && d.visit(v, path) {
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			d.buf.WriteString(v.Type().String() + "(" + strconv.Quote(string(byteSlice(v))) + ")")
+			return
+		}
+		d.buf.WriteString(v.Type().String() + "{")
+		if v.Len() > 0 {
+			d.buf.WriteString("\n")
+		}
+		for kk := 0; kk < v.Len(); kk++ {
+			d.buf.WriteString(indent + "  ")
+			d.dump(v.Index(kk), fmt.Sprintf("%s[%d]", path, kk), indent+"  ")
+			d.buf.WriteString(",\n")
+		}
+		if v.Len() > 0 {
+			d.buf.WriteString(indent)
+		}
+		d.buf.WriteString("}")
+	case reflect.Map:
+		if v.IsNil() {
+			d.buf.WriteString(v.Type().String() + "(nil)")
+			return
+		}
+		if d.visit(v, path) {
+			return
+		}
+		d.dumpMap(v, path, indent)
+	default:
+		d.buf.WriteString(v.Type().String())
+	}
+}
+
+func (d *dumper) dumpMap(v reflect.Value, path, indent string) {
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+
+	var entries []entry
+	for _, key := range v.MapKeys() {
+		kd := &dumper{seen: map[dumpRef]string{}}
+		kd.dump(key, "", indent+"  ")
+		entries = append(entries, entry{kd.buf.String(), v.MapIndex(key)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	d.buf.WriteString(v.Type().String() + "{")
+	if len(entries) > 0 {
+		d.buf.WriteString("\n")
+	}
+	for _, e := range entries {
+		d.buf.WriteString(indent + "  " + e.key + ": ")
+		d.dump(e.val, path+"["+e.key+"]", indent+"  ")
+		d.buf.WriteString(",\n")
+	}
+	if len(entries) > 0 {
+		d.buf.WriteString(indent)
+	}
+	d.buf.WriteString("}")
+}
+
+// visit records the path of the pointer, map or slice, writing a
+// back-reference and returning true if it was seen before.
+func (d *dumper) visit(v reflect.Value, path string) bool {
+	ref := dumpRef{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		ref.len = v.Len()
+	}
+	if first, ok := d.seen[ref]; ok {
+		d.buf.WriteString("<ref " + first + ">")
+		return true
+	}
+	d.seen[ref] = path
+	return false
+}
+
+// exposed returns an equivalent value which can be used with
+// Interface even if it was reached via unexported fields.
+func exposed(v reflect.Value) reflect.Value {
+	if v.CanInterface() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+func byteSlice(v reflect.Value) []byte {
+	result := make([]byte, v.Len())
+	for kk := range result {
+		result[kk] = byte(v.Index(kk).Uint())
+	}
+	return result
+}
+
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits)
+}
+
+// dumpError renders the error type and message along with the errors
+// it wraps.
+func dumpError(err error) string {
+	s := fmt.Sprintf("%T(%s", err, strconv.Quote(err.Error()))
+
+	var wrapped []string
+	switch x := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range x.Unwrap() {
+			if inner != nil {
+				wrapped = append(wrapped, dumpError(inner))
+			}
+		}
+	default:
+		if inner := errors.Unwrap(err); inner != nil {
+			wrapped = append(wrapped, dumpError(inner))
+		}
+	}
+
+	if len(wrapped) > 0 {
+		s += " wraps " + strings.Join(wrapped, ", ")
+	}
+	return s + ")"
+}
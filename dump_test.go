@@ -0,0 +1,73 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tvastar/test"
+)
+
+type node struct {
+	Name     string
+	next     *node
+	err      error
+	weights  []float64
+	attrs    map[string]interface{}
+	callback func(int) string
+	events   chan int
+	created  time.Time
+	raw      []byte
+	empty    struct{}
+}
+
+type multiError []error
+
+func (m multiError) Error() string {
+	return fmt.Sprint([]error(m))
+}
+
+func (m multiError) Unwrap() []error {
+	return m
+}
+
+func graph() *node {
+	root := &node{Name: "root", err: fmt.Errorf("load: %w", io.EOF)}
+	child := &node{Name: "child", next: root, weights: []float64{math.NaN(), math.Inf(1), -0.5}}
+	root.next = child
+	root.attrs = map[string]interface{}{"z": 1, "a": child, "m": errors.New("plain")}
+	root.callback = func(int) string { return "" }
+	root.events = make(chan int, 3)
+	root.created = time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+	root.raw = []byte("bytes\x00")
+	return root
+}
+
+func TestDump(t *testing.T) {
+	test.Artifact(t.Error, "dump_graph.dump", graph(), test.Format(test.Dump))
+	test.Artifact(t.Error, "dump_values.dump", func() interface{} {
+		return []interface{}{nil, func() {}, complex(1, -2), uint8(3), (*node)(nil), multiError{io.EOF, io.ErrUnexpectedEOF}}
+	}, test.Format(test.Dump), test.Repeat(2))
+}
+
+func TestDumpMismatch(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+
+	g := graph()
+	g.next.weights[0] = 1
+	test.Artifact(errorf, "dump_graph.dump", g, test.Format(test.Dump))
+	if len(failed) != 2 || failed[0] != "unexpected output" || !strings.Contains(failed[1].(string), `"      NaN,"`) || !strings.Contains(failed[1].(string), `"      1,"`) {
+		t.Errorf("Unexpected failure %#v", failed)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Exec implements golden testing of command-line programs.
+//
+// The pkg is the main package to run: either an import path or a
+// path relative to the directory of the caller (such as
+// "./cmd/testmd").  It is built into the temporary work directory
+// (relying on the go build cache to keep this fast).
+//
+// The inputFile and outputFile are relative to the testdata/ folder
+// of the caller.  The input is a JSON file describing the command:
+//
+//    {
+//      "args": ["-o", "$WORK/out.go", "$TESTDATA/input.md"],
+//      "env": {"GOFLAGS": "-mod=mod"},
+//      "dir": "$TESTDATA",
+//      "stdin": "..."
+//    }
+//
+// The command runs in a fresh temporary directory ($WORK) unless dir
+// is specified.  The args, env and dir can refer to $WORK, $TESTDATA
+// (the testdata folder of the caller) and $BIN (the built program).
+//
+// The output is a JSON file holding the stdout, stderr and exit code
+// of the command, with the paths above replaced by their
+// placeholders.  It is compared as with Artifact.
+//
+// If the tests are run with -golden flag, the output is not compared
+// but instead the output files are generated.
+//
+// Example Usage:
+//
+//    test.Exec(t.Error, "./cmd/testmd", "usage.json", "usage.golden.json")
+//
+func Exec(errorf Errorf, pkg, inputFile, outputFile string) {
+	testdata := callerTestdata(2)
+	inputFile = filepath.Join(testdata, inputFile)
+	outputFile = filepath.Join(testdata, outputFile)
+
+	var input struct {
+		Args  []string
+		Env   map[string]string
+		Dir   string
+		Stdin string
+	}
+
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		errorf("error reading", inputFile, err)
+		return
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		errorf("could not unmarshal", inputFile, err)
+		return
+	}
+
+	work, err := ioutil.TempDir("", "test-exec")
+	if err != nil {
+		errorf("could not create work directory", err)
+		return
+	}
+	defer os.RemoveAll(work)
+
+	bin, err := build(filepath.Dir(testdata), pkg, work)
+	if err != nil {
+		errorf("could not build", pkg, err)
+		return
+	}
+
+	vars := map[string]string{"WORK": work, "TESTDATA": testdata, "BIN": bin}
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			return "$" + name
+		})
+	}
+
+	cmd := exec.Command(bin)
+	for _, arg := range input.Args {
+		cmd.Args = append(cmd.Args, expand(arg))
+	}
+	cmd.Dir = work
+	if input.Dir != "" {
+		cmd.Dir = expand(input.Dir)
+		if !filepath.IsAbs(cmd.Dir) {
+			cmd.Dir = filepath.Join(work, cmd.Dir)
+		}
+	}
+	cmd.Env = os.Environ()
+	for _, key := range sortedKeys(input.Env) {
+		cmd.Env = append(cmd.Env, key+"="+expand(input.Env[key]))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(input.Stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			errorf("could not run", pkg, err)
+			return
+		}
+		code = exit.ExitCode()
+	}
+
+	result := struct {
+		Stdout string `json:"stdout"`
+		Stderr string `json:"stderr"`
+		Exit   int    `json:"exit"`
+	}{placeholders(stdout.String(), vars), placeholders(stderr.String(), vars), code}
+	artifact(errorf, outputFile, result)
+}
+
+// placeholders replaces the values of vars in s with $name, longest
+// values first.
+func placeholders(s string, vars map[string]string) string {
+	names := sortedKeys(vars)
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(vars[names[i]]) > len(vars[names[j]])
+	})
+	for _, name := range names {
+		if vars[name] != "" {
+			s = strings.Replace(s, vars[name], "$"+name, -1)
+		}
+	}
+	return s
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// build builds the main package (relative to dir) into the output
+// directory.
+func build(dir, pkg, output string) (string, error) {
+	if strings.HasPrefix(pkg, ".") {
+		pkg = filepath.Join(dir, pkg)
+	}
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return "", err
+	}
+
+	bin := filepath.Join(output, filepath.Base(pkg))
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	cmd := exec.Command("go", "build", "-o", bin, pkg)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.New(string(output))
+	}
+	return bin, nil
+}
@@ -0,0 +1,39 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func TestExec(t *testing.T) {
+	test.Exec(t.Error, "./testdata/cat", "exec_cat.json", "exec_cat.golden.json")
+	test.Exec(t.Error, "./cmd/testmd", "exec_testmd.json", "exec_testmd.golden.json")
+	test.Exec(t.Error, "./testdata/usage", "exec_usage.json", "exec_usage.golden.json")
+}
+
+func TestExecFailures(t *testing.T) {
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+
+	test.Exec(errorf, "./testdata/cat", "non-existent", "non-existent")
+	test.Exec(errorf, "./testdata/cat", "input.txt", "non-existent")
+	test.Exec(errorf, "./non-existent", "exec_cat.json", "non-existent")
+	test.Exec(errorf, "./testdata/cat", "exec_cat.json", "non-existent")
+
+	expected := []interface{}{"error reading", "could not unmarshal", "could not build", "error reading"}
+	if len(reports) != len(expected) {
+		t.Fatal("Unexpected reports", reports)
+	}
+	for kk := range expected {
+		if reports[kk] != expected[kk] {
+			t.Error("Unexpected report", kk, reports[kk])
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/imports"
+)
+
+// Fake generates a typed recorder and a replay fake for the named
+// interface in the package with the provided import path.  The
+// output is written to dest as part of package pkg.
+//
+// The package is loaded from source via go/types relative to the
+// current directory.  For an interface named Store, the generated
+// code has two types which implement the interface using Calls:
+//
+//    type StoreRecorder struct{ Calls *test.Calls }
+//    type StoreFake struct{ Calls *test.Calls }
+//
+// StoreRecorder forwards every method to Calls.Call (which invokes
+// Calls.Target and logs the call) while StoreFake serves every
+// method via Calls.Replay from a log loaded via Calls.Load.
+//
+// The code is formatted with go/format and imports.Process as with
+// Markdown.
+func Fake(importPath, name, dest, pkg string) error {
+	imp := importer.ForCompiler(token.NewFileSet(), "source", nil).(types.ImporterFrom)
+	p, err := imp.ImportFrom(importPath, ".", 0)
+	if err != nil {
+		return err
+	}
+
+	obj := p.Scope().Lookup(name)
+	if obj == nil {
+		return fmt.Errorf("%s.%s not found", importPath, name)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return fmt.Errorf("%s.%s is not an interface", importPath, name)
+	}
+
+	imported := map[string]string{"github.com/tvastar/test": "test"}
+	qualifier := func(other *types.Package) string {
+		if other.Name() == pkg {
+			return ""
+		}
+		imported[other.Path()] = other.Name()
+		return other.Name()
+	}
+
+	f := fakeInfo{
+		Package:   pkg,
+		Name:      name,
+		Interface: types.TypeString(obj.Type(), qualifier),
+	}
+	for kk := 0; kk < iface.NumMethods(); kk++ {
+		m := iface.Method(kk)
+		if !m.Exported() && m.Pkg().Name() != pkg {
+			return fmt.Errorf("%s.%s has unexported method %s", importPath, name, m.Name())
+		}
+		f.Methods = append(f.Methods, fakeMethod(m, qualifier))
+	}
+
+	for p, name := range imported {
+		if path.Base(p) == name {
+			name = ""
+		}
+		f.Imports = append(f.Imports, [2]string{name, p})
+	}
+	sort.Slice(f.Imports, func(i, j int) bool {
+		return f.Imports[i][1] < f.Imports[j][1]
+	})
+
+	var buf bytes.Buffer
+	if err := fakeTpl.Execute(&buf, f); err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errors.New(err.Error() + "\n" + buf.String())
+	}
+
+	result, err := imports.Process(dest, src, nil)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, result, 0644)
+}
+
+func fakeMethod(m *types.Func, qualifier types.Qualifier) methodInfo {
+	sig := m.Type().(*types.Signature)
+	info := methodInfo{Name: m.Name()}
+
+	reserved := map[string]bool{"x": true, "results": true}
+	for kk := 0; kk < sig.Results().Len(); kk++ {
+		reserved[fmt.Sprintf("r%d", kk)] = true
+	}
+
+	var params, args []string
+	for kk := 0; kk < sig.Params().Len(); kk++ {
+		v := sig.Params().At(kk)
+		name := v.Name()
+		if name == "" || name == "_" || reserved[name] {
+			name = fmt.Sprintf("a%d", kk)
+		}
+
+		typ := types.TypeString(v.Type(), qualifier)
+		if sig.Variadic() && kk == sig.Params().Len()-1 {
+			typ = "..." + types.TypeString(v.Type().(*types.Slice).Elem(), qualifier)
+		}
+		params = append(params, name+" "+typ)
+		args = append(args, name)
+	}
+	info.Params = strings.Join(params, ", ")
+	info.Args = strings.Join(args, ", ")
+
+	var results []string
+	for kk := 0; kk < sig.Results().Len(); kk++ {
+		typ := types.TypeString(sig.Results().At(kk).Type(), qualifier)
+		results = append(results, typ)
+		info.Results = append(info.Results, [2]string{fmt.Sprintf("r%d", kk), typ})
+	}
+	info.ResultTypes = strings.Join(results, ", ")
+	if len(results) > 1 {
+		info.ResultTypes = "(" + info.ResultTypes + ")"
+	}
+	return info
+}
+
+type fakeInfo struct {
+	Package   string
+	Name      string
+	Interface string
+	Imports   [][2]string
+	Methods   []methodInfo
+}
+
+type methodInfo struct {
+	Name        string
+	Params      string
+	Args        string
+	Results     [][2]string
+	ResultTypes string
+}
+
+var fakeTpl = template.Must(template.New("fake").Parse(`
+// Code generated by github.com/tvastar/test/cmd/testfake. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+  {{range $import := .Imports}}{{with index $import 0}}{{.}} {{end}}"{{index $import 1}}"
+  {{end -}}
+)
+
+// {{.Name}}Recorder implements {{.Interface}} by forwarding calls to
+// Calls.Target and recording them.
+type {{.Name}}Recorder struct {
+	Calls *test.Calls
+}
+{{range $m := .Methods}}
+// {{$m.Name}} implements {{$.Interface}}.
+func (x {{$.Name}}Recorder) {{$m.Name}}({{$m.Params}}) {{$m.ResultTypes}} {
+	{{if $m.Results}}results := {{end}}x.Calls.Call("{{$m.Name}}"{{if $m.Args}}, {{$m.Args}}{{end}})
+	{{- range $idx, $r := $m.Results}}
+	{{index $r 0}}, _ := results[{{$idx}}].({{index $r 1}})
+	{{- end}}
+	{{- if $m.Results}}
+	return {{range $idx, $r := $m.Results}}{{if $idx}}, {{end}}{{index $r 0}}{{end}}
+	{{- end}}
+}
+{{end}}
+// {{.Name}}Fake implements {{.Interface}} by replaying calls recorded
+// in the log loaded into Calls.
+type {{.Name}}Fake struct {
+	Calls *test.Calls
+}
+{{range $m := .Methods}}
+// {{$m.Name}} implements {{$.Interface}}.
+func (x {{$.Name}}Fake) {{$m.Name}}({{$m.Params}}) {{$m.ResultTypes}} {
+	{{- range $r := $m.Results}}
+	var {{index $r 0}} {{index $r 1}}
+	{{- end}}
+	x.Calls.Replay("{{$m.Name}}", []interface{}{ {{- $m.Args -}} }
+	{{- range $r := $m.Results}}, &{{index $r 0}}{{end}})
+	{{- if $m.Results}}
+	return {{range $idx, $r := $m.Results}}{{if $idx}}, {{end}}{{index $r 0}}{{end}}
+	{{- end}}
+}
+{{end}}
+`))
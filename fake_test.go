@@ -0,0 +1,104 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+//go:generate go run ./cmd/testfake -o store_fake_test.go -pkg test_test github.com/tvastar/test/testdata/store Store
+
+func TestFake(t *testing.T) {
+	test.File(t.Fatal, "store/store.go", "../store_fake_test.go", func(src string) (string, error) {
+		outf, err := ioutil.TempFile("", "*_fake_test.go")
+		if err != nil {
+			return "", err
+		}
+		n := outf.Name()
+		ignore(outf.Close())
+
+		defer func() { ignore(os.Remove(n)) }()
+		if err := test.Fake("github.com/tvastar/test/testdata/store", "Store", n, "test_test"); err != nil {
+			return "", err
+		}
+
+		data, err := ioutil.ReadFile(n)
+		return string(data), err
+	})
+}
+
+func TestFakeErrors(t *testing.T) {
+	if err := test.Fake("github.com/tvastar/test/non-existent", "Store", "boo", "main"); err == nil {
+		t.Error("Did not fail on non-existent package")
+	}
+	if err := test.Fake("github.com/tvastar/test/testdata/store", "Missing", "boo", "main"); err == nil {
+		t.Error("Did not fail on non-existent interface")
+	}
+	if err := test.Fake("io", "Copy", "boo", "main"); err == nil {
+		t.Error("Did not fail on non-interface")
+	}
+}
+
+type closableStore struct {
+	mapStore
+}
+
+func (closableStore) Close() error {
+	return nil
+}
+
+func useFullStore(s interface {
+	store
+	io.Closer
+}) string {
+	defer s.Close()
+	useStore(s)
+	v, _ := s.Get("a/2")
+	_, err := s.Get("c")
+	keys := s.Keys("a/")
+	return strings.Join(append(keys, v, err.Error()), ",")
+}
+
+func TestFakeRecordReplay(t *testing.T) {
+	calls := &test.Calls{Target: closableStore{mapStore{}}}
+	recorded := useFullStore(StoreRecorder{calls})
+	calls.Check(t.Error, "fake_store.json")
+
+	calls = &test.Calls{}
+	calls.Load(t.Error, "fake_store.json")
+	replayed := useFullStore(StoreFake{calls})
+	if recorded != replayed {
+		t.Error("Unexpected replay", recorded, replayed)
+	}
+	calls.Check(t.Error, "fake_store.json")
+}
+
+func TestFakeReplayMismatch(t *testing.T) {
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+
+	calls := &test.Calls{}
+	calls.Load(errorf, "fake_store.json")
+	fake := StoreFake{calls}
+	if err := fake.Put("a/1", "uno"); err != nil {
+		t.Error("Unexpected error", err)
+	}
+	if err := fake.Put("a/1", "one"); err != nil {
+		t.Error("Unexpected error", err)
+	}
+	calls.Load(errorf, "non-existent")
+
+	if len(reports) != 2 || reports[0] != "unexpected call" || reports[1] != "error reading" {
+		t.Error("Unexpected reports", reports)
+	}
+}
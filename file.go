@@ -7,14 +7,18 @@ package test
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
+	"io/fs"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
 )
 
 // File implements testing against input/output files
@@ -31,7 +35,12 @@ import (
 // function. For input arguments of type string, []byte or []rune the
 // contents of the files are passed as is. For other types,  the
 // contents are assumed to be JSON encoded.  The output is similarly
-// JSON encoded for such types.
+// JSON encoded for such types unless it implements GoldenMarshaler.
+//
+// For input arguments of an interface type implemented by the file
+// systems of os.DirFS (such as fs.FS or fs.ReadFileFS), the input file
+// names either a directory or a txtar archive (with a .txtar
+// extension) whose files make up the file system.
 //
 // The discrepancies are reported using regular diff format via the
 // error function (which sports the same signature as testing.T.Error
@@ -41,82 +50,232 @@ import (
 // but instead the output files are created to match the output
 // provided by the test.
 //
+// Options such as Repeat can be used to check that the output is
+// deterministic before it is compared.
+//
+// The output file can have Go version or platform specific variants,
+// be compressed or be stored as an object as with Artifact.
+//
 // Example Usage:
 //
 //    test.File(t.Fatal, "input.txt", "output.txt",
 //       func(input string) string { .... },
 //    )
 //
-func File(errorf Errorf, inputFile string, outputFile string, fn interface{}) {
-	pc := []uintptr{0}
-	runtime.Callers(2, pc)
-	f, _ := runtime.CallersFrames(pc).Next()
+func File(errorf Errorf, inputFile string, outputFile string, fn interface{}, opts ...Option) {
+	dir := callerTestdata(2)
+	inputFile = filepath.Join(dir, inputFile)
+	outputFile = filepath.Join(dir, outputFile)
+	file(errorf, inputFile, outputFile, fn, opts...)
+}
 
-	inputFile = filepath.Join(filepath.Dir(f.File), "testdata/"+inputFile)
-	outputFile = filepath.Join(filepath.Dir(f.File), "testdata/"+outputFile)
+func file(errorf Errorf, inputFile, outputFile string, fn interface{}, opts ...Option) {
+	o := newOptions(opts)
 
-	bytes, err := ioutil.ReadFile(inputFile)
-	if err != nil {
-		errorf("error reading", inputFile, err)
-		return
+	var call func(run int) (string, error)
+	if v := reflect.ValueOf(fn); v.Type().In(0).Implements(fsType) {
+		fsys, cleanup, err := inputFS(inputFile)
+		if err != nil {
+			errorf("error reading", inputFile, err)
+			return
+		}
+		defer cleanup()
+
+		arg := reflect.ValueOf(fsys)
+		if !arg.Type().AssignableTo(v.Type().In(0)) {
+			errorf("unsupported file system type", v.Type().In(0).String())
+			return
+		}
+		call = func(int) (string, error) {
+			return encode(v.Call([]reflect.Value{arg}))
+		}
+	} else {
+		bytes, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			errorf("error reading", inputFile, err)
+			return
+		}
+		call = func(run int) (string, error) {
+			return invoke(fn, o.input(run, string(bytes), reflect.TypeOf(fn).In(0)))
+		}
 	}
 
-	output, err := invoke(fn, string(bytes))
-	if err != nil {
-		errorf(err)
+	output, ok := o.stable(errorf, func(run int) (string, bool) {
+		output, err := call(run)
+		if err != nil {
+			errorf(err)
+		}
+		return output, err == nil
+	})
+	if !ok {
 		return
 	}
 
 	if *goldenFlag {
-		if err := ioutil.WriteFile(outputFile, []byte(output), 0644); err != nil {
+		if err := writeGolden(outputFile, []byte(output), o.objectSize); err != nil {
 			errorf("Could not save golden output", outputFile, err)
 		}
 		return
 	}
 
-	bytes, err = ioutil.ReadFile(outputFile)
+	bytes, err := readGolden(outputFile)
 	if err != nil {
 		errorf("error reading", outputFile, err)
 		return
 	}
 
 	if output != string(bytes) {
-		errorf("unexpected output", linediff(string(bytes), output))
+		errorf("unexpected output", summarizeDiff(linediff(string(bytes), output)))
+	}
+}
+
+// callerTestdata returns the testdata folder next to the source file
+// of the caller skip frames up the stack.
+func callerTestdata(skip int) string {
+	pc := []uintptr{0}
+	runtime.Callers(skip+1, pc)
+	f, _ := runtime.CallersFrames(pc).Next()
+	return filepath.Join(filepath.Dir(f.File), "testdata")
+}
+
+// fixture is an input file and the golden file it maps to.
+type fixture struct {
+	name, input, output string
+}
+
+// fixtures expands inputGlob within dir.  The part of each file name
+// matched by the first '*' of the glob is the fixture name and is
+// substituted for the '*' in outputPattern to get the golden file.
+func fixtures(dir, inputGlob, outputPattern string) ([]fixture, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, inputGlob))
+	if err != nil {
+		return nil, err
 	}
+
+	idx := strings.Index(inputGlob, "*")
+	prefix, suffix := "", ""
+	if idx >= 0 {
+		prefix, suffix = inputGlob[:idx], inputGlob[idx+1:]
+	}
+
+	result := make([]fixture, 0, len(matches))
+	for _, m := range matches {
+		rel, err := filepath.Rel(dir, m)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		name := rel
+		if idx >= 0 && strings.HasPrefix(rel, prefix) && strings.HasSuffix(rel[len(prefix):], suffix) {
+			name = rel[len(prefix) : len(rel)-len(suffix)]
+		}
+		output := ""
+		if outputPattern != "" {
+			output = filepath.Join(dir, strings.Replace(outputPattern, "*", name, 1))
+		}
+		result = append(result, fixture{name, m, output})
+	}
+	return result, nil
 }
 
 func linediff(s1, s2 string) string {
 	return cmp.Diff(strings.Split(s1, "\n"), strings.Split(s2, "\n"))
 }
 
+var fsType = reflect.TypeOf((*fs.FS)(nil)).Elem()
+
+// inputFS returns the file system for an input directory or txtar
+// archive.  Archives are extracted into a temporary directory which
+// is removed by the returned cleanup function.
+func inputFS(name string) (fs.FS, func(), error) {
+	cleanup := func() {}
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if info.IsDir() {
+		return os.DirFS(name), cleanup, nil
+	}
+	if filepath.Ext(name) != ".txtar" {
+		return nil, cleanup, errors.New("not a directory or txtar archive")
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	dir, err := ioutil.TempDir("", "test-fs")
+	if err != nil {
+		return nil, cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	for _, f := range txtar.Parse(data).Files {
+		if !fs.ValidPath(f.Name) {
+			cleanup()
+			return nil, func() {}, errors.New("invalid archive file name " + f.Name)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(path, f.Data, 0644)
+		}
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+	}
+	return os.DirFS(dir), cleanup, nil
+}
+
 func invoke(fn interface{}, input string) (string, error) {
 	v := reflect.ValueOf(fn)
-	argType := v.Type().In(0)
-	var results []reflect.Value
+	arg, err := decode(v.Type().In(0), input)
+	if err != nil {
+		return "", err
+	}
+	return encode(v.Call([]reflect.Value{arg}))
+}
+
+// isText reports whether values of the type are passed as text
+// rather than JSON encoded or decoded.
+func isText(t reflect.Type) bool {
+	switch reflect.Zero(t).Interface().(type) {
+	case string, []byte, []rune:
+		return true
+	}
+	return false
+}
 
+func decode(argType reflect.Type, input string) (reflect.Value, error) {
 	switch reflect.Zero(argType).Interface().(type) {
 	case string:
-		results = v.Call([]reflect.Value{reflect.ValueOf(input)})
+		return reflect.ValueOf(input), nil
 	case []byte:
-		bytes := []byte(input)
-		results = v.Call([]reflect.Value{reflect.ValueOf(bytes)})
+		return reflect.ValueOf([]byte(input)), nil
 	case []rune:
-		runes := []rune(input)
-		results = v.Call([]reflect.Value{reflect.ValueOf(runes)})
-	default:
-		ptr := reflect.New(argType)
-		if err := json.Unmarshal([]byte(input), ptr.Interface()); err != nil {
-			return "", err
-		}
-		results = v.Call([]reflect.Value{ptr.Elem()})
+		return reflect.ValueOf([]rune(input)), nil
 	}
 
+	ptr := reflect.New(argType)
+	if err := json.Unmarshal([]byte(input), ptr.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return ptr.Elem(), nil
+}
+
+func encode(results []reflect.Value) (string, error) {
 	if len(results) > 1 {
 		if err, _ := results[1].Interface().(error); err != nil {
 			return "", err
 		}
 	}
 
+	if m, ok := goldenMarshaler(results[0].Interface()); ok {
+		bytes, err := m.MarshalGolden()
+		return string(bytes), err
+	}
+
 	switch r := results[0].Interface().(type) {
 	case string:
 		return r, nil
@@ -126,7 +285,11 @@ func invoke(fn interface{}, input string) (string, error) {
 		return string(r), nil
 	}
 
-	bytes, err := json.MarshalIndent(results[0].Interface(), "", "\t")
+	v, err := goldenJSON(results[0].Interface())
+	if err != nil {
+		return "", err
+	}
+	bytes, err := json.MarshalIndent(v, "", "\t")
 	if err != nil {
 		return "", err
 	}
@@ -7,12 +7,14 @@ package test_test
 import (
 	"errors"
 	"flag"
+	"io/fs"
 	"os"
 	"path"
 	"regexp"
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/tvastar/test"
 )
@@ -159,6 +161,40 @@ func TestFileGoldenWriteFail(t *testing.T) {
 	}
 }
 
+func listFS(fsys fs.FS) (map[string]int, error) {
+	sizes := map[string]int{}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		sizes[path] = len(data)
+		return err
+	})
+	return sizes, err
+}
+
+func TestFileFS(t *testing.T) {
+	test.File(t.Error, "store", "fs_store.json", listFS)
+	test.File(t.Error, "fs_input.txtar", "fs_txtar.json", listFS)
+	test.File(t.Error, "fs_input.txtar", "fs_txtar.json", func(fsys fs.ReadFileFS) (map[string]int, error) {
+		return listFS(fsys)
+	})
+
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+	test.File(errorf, "input.txt", "fs_txtar.json", listFS)
+	test.File(errorf, "non-existent", "fs_txtar.json", listFS)
+	test.File(errorf, "store", "fs_store.json", func(fsys fstest.MapFS) (map[string]int, error) {
+		return listFS(fsys)
+	})
+	if len(failed) != 3 || failed[0] != "error reading" || failed[1] != "error reading" || failed[2] != "unsupported file system type" {
+		t.Error("Unexpected failures", failed)
+	}
+}
+
 func setGoldenFlag(enable bool) {
 	v := "false"
 	if enable {
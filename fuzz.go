@@ -0,0 +1,158 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Fuzz uses the File fixtures matching inputGlob as the seed corpus
+// of a fuzz test.
+//
+// The inputGlob and outputPattern are relative to the testdata/
+// folder of the caller.  The part of the input file name matched by
+// the '*' in inputGlob is substituted into outputPattern to find the
+// golden output of each fixture.  The provided function has the same
+// form as with File.
+//
+// Every fixture is first verified against its golden output (as
+// with File) and then registered via f.Add.  The fuzz target simply
+// invokes the function: panics are failures while errors (including
+// inputs that cannot be decoded) are not.
+//
+// If the tests are run with the -golden flag, the entries found in
+// testdata/fuzz/FuzzXxx (where FuzzXxx is the name of the fuzz test)
+// are first converted into fixtures named "fuzz-<entry>" with their
+// golden outputs recorded.  This way, inputs found by the fuzzer
+// become permanent regression tests.  This requires inputGlob to
+// have a '*'.
+//
+// Example Usage:
+//
+//    func FuzzParse(f *testing.F) {
+//        test.Fuzz(f, "parse/*.in.txt", "parse/*.out.json", parse)
+//    }
+//
+func Fuzz(f *testing.F, inputGlob, outputPattern string, fn interface{}) {
+	dir := callerTestdata(2)
+
+	if *goldenFlag {
+		corpus := filepath.Join(dir, "fuzz", f.Name())
+		if err := convertCorpus(corpus, dir, inputGlob); err != nil {
+			f.Error("could not convert fuzz corpus", corpus, err)
+		}
+	}
+
+	list, err := fixtures(dir, inputGlob, outputPattern)
+	if err != nil {
+		f.Fatal("invalid input glob", inputGlob, err)
+	}
+
+	asBytes := reflect.TypeOf(fn).In(0) == reflect.TypeOf([]byte(nil))
+	for _, fx := range list {
+		file(f.Error, fx.input, fx.output, fn)
+
+		data, err := ioutil.ReadFile(fx.input)
+		if err != nil {
+			continue
+		}
+		if asBytes {
+			f.Add(data)
+		} else {
+			f.Add(string(data))
+		}
+	}
+
+	if asBytes {
+		f.Fuzz(func(t *testing.T, input []byte) {
+			_, _ = invoke(fn, string(input))
+		})
+	} else {
+		f.Fuzz(func(t *testing.T, input string) {
+			_, _ = invoke(fn, input)
+		})
+	}
+}
+
+// convertCorpus writes every entry of the fuzz corpus directory as an
+// input fixture named by substituting "fuzz-<entry>" into inputGlob.
+func convertCorpus(corpus, dir, inputGlob string) error {
+	entries, err := ioutil.ReadDir(corpus)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(inputGlob, "*") {
+		return errors.New("input glob without '*' cannot name corpus fixtures")
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		input, err := readCorpusEntry(filepath.Join(corpus, entry.Name()))
+		if err != nil {
+			return err
+		}
+		name := strings.Replace(inputGlob, "*", "fuzz-"+entry.Name(), 1)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(input), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCorpusEntry parses a file in the "go test fuzz v1" format
+// holding a single string or []byte value.
+func readCorpusEntry(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || strings.TrimSpace(lines[0]) != "go test fuzz v1" {
+		return "", errors.New("unsupported fuzz corpus entry " + path)
+	}
+
+	expr, err := parser.ParseExpr(lines[1])
+	if err != nil {
+		return "", err
+	}
+
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 || !isStringOrBytes(call.Fun) {
+		return "", errors.New("unsupported fuzz corpus value in " + path)
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", errors.New("unsupported fuzz corpus value in " + path)
+	}
+	return strconv.Unquote(lit.Value)
+}
+
+func isStringOrBytes(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name == "string"
+	case *ast.ArrayType:
+		elt, ok := fun.Elt.(*ast.Ident)
+		return ok && fun.Len == nil && elt.Name == "byte"
+	}
+	return false
+}
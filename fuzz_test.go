@@ -0,0 +1,64 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func shout(input string) string {
+	return strings.ToUpper(input)
+}
+
+func FuzzShout(f *testing.F) {
+	test.Fuzz(f, "cases/*.in.txt", "cases/*.out.txt", shout)
+}
+
+func FuzzShoutBytes(f *testing.F) {
+	test.Fuzz(f, "cases/*.in.txt", "cases/*.out.txt", func(input []byte) []byte {
+		return []byte(shout(string(input)))
+	})
+}
+
+func FuzzShoutCorpus(f *testing.F) {
+	_, fname, _, _ := runtime.Caller(0)
+	dir := path.Join(path.Dir(fname), "testdata")
+	corpus := path.Join(dir, "fuzz", f.Name())
+
+	// only the corpus of this test is removed along with the fuzz
+	// directory if it is otherwise empty
+	check(os.MkdirAll(corpus, 0755))
+	defer os.Remove(path.Join(dir, "fuzz"))
+	defer os.RemoveAll(corpus)
+	defer os.Remove(path.Join(dir, "cases/fuzz-crasher.in.txt"))
+	defer os.Remove(path.Join(dir, "cases/fuzz-crasher.out.txt"))
+
+	entry := "go test fuzz v1\nstring(\"found\\nit\")\n"
+	check(ioutil.WriteFile(path.Join(corpus, "crasher"), []byte(entry), 0644))
+
+	defer restoreGoldenFlag()()
+	check(flag.Set("golden", "true"))
+	test.Fuzz(f, "cases/*.in.txt", "cases/*.out.txt", shout)
+
+	data, err := ioutil.ReadFile(path.Join(dir, "cases/fuzz-crasher.out.txt"))
+	if err != nil || string(data) != "FOUND\nIT" {
+		f.Error("unexpected golden output", string(data), err)
+	}
+}
+
+func FuzzSingleFixture(f *testing.F) {
+	// a glob without '*' names a single fixture
+	test.Fuzz(f, "input.txt", "output.txt", func(input string) string {
+		return input
+	})
+}
@@ -1,11 +1,18 @@
 module github.com/tvastar/test
 
-go 1.12
+go 1.18
 
 require (
-	github.com/golangci/golangci-lint v1.18.0 // indirect
 	github.com/google/go-cmp v0.3.1
+	github.com/klauspost/compress v1.15.15
 	github.com/russross/blackfriday/v2 v2.0.1
+	golang.org/x/net v0.0.0-20220722155237-a158d28d115b
+	golang.org/x/tools v0.1.12
+)
+
+require (
+	github.com/golangci/golangci-lint v1.18.0 // indirect
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
-	golang.org/x/tools v0.0.0-20190909030654-5b82db07426d
+	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
+	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
 )
@@ -0,0 +1,458 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoldenMarshaler is implemented by types with a natural textual form
+// (such as a graph rendered as DOT) which should be used for golden
+// files instead of the default encoding.
+//
+// When the value passed to Artifact (or the output of the function
+// passed to File) implements GoldenMarshaler, the golden file holds
+// the bytes returned by MarshalGolden and is compared line by line
+// (or via DiffGolden if the value also implements GoldenDiffer).
+//
+// Nested values which implement GoldenMarshaler are stored as strings
+// holding their golden form by the JSON codec and the output of File.
+// The Dump codec similarly renders them as their golden form.
+type GoldenMarshaler interface {
+	MarshalGolden() ([]byte, error)
+}
+
+// GoldenDiffer can be implemented by a GoldenMarshaler to control how
+// its golden form is compared.  DiffGolden returns an empty string if
+// the expected and actual forms are equivalent.
+type GoldenDiffer interface {
+	DiffGolden(expected, actual []byte) (string, error)
+}
+
+var (
+	goldenMarshalerType = reflect.TypeOf((*GoldenMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// goldenMarshaler returns value as a GoldenMarshaler if it implements
+// the interface and is not a nil pointer.
+func goldenMarshaler(value interface{}) (GoldenMarshaler, bool) {
+	m, ok := value.(GoldenMarshaler)
+	if !ok || isNilValue(reflect.ValueOf(value)) {
+		return nil, false
+	}
+	return m, true
+}
+
+// goldenDiff compares the golden forms of value.
+func goldenDiff(value interface{}, expected, actual []byte) (string, error) {
+	if d, ok := value.(GoldenDiffer); ok {
+		return d.DiffGolden(expected, actual)
+	}
+	if bytes.Equal(expected, actual) {
+		return "", nil
+	}
+	return linediff(string(expected), string(actual)), nil
+}
+
+// goldenJSON returns a value which encodes to JSON like value except
+// that nested GoldenMarshalers are encoded as their golden form.  The
+// value is returned as is if there are no such nested values.
+func goldenJSON(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || !hasGoldenMarshaler(v, map[dumpRef]bool{}) {
+		return value, nil
+	}
+	return jsonValue(v)
+}
+
+// hasGoldenMarshaler reports whether v contains a GoldenMarshaler
+// reachable via fields encoded by encoding/json.
+func hasGoldenMarshaler(v reflect.Value, seen map[dumpRef]bool) bool {
+	if !v.IsValid() {
+		return false
+	}
+	if _, ok := goldenMarshaler(v.Interface()); ok {
+		return true
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return false
+		}
+		ref := dumpRef{v.Pointer(), v.Type(), 0}
+		if v.Kind() == reflect.Slice {
+			ref.len = v.Len()
+		}
+		if seen[ref] {
+			return false
+		}
+		seen[ref] = true
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return hasGoldenMarshaler(v.Elem(), seen)
+	case reflect.Struct:
+		for _, f := range jsonFields(v.Type()) {
+			if fv, ok := jsonFieldValue(v, f.index); ok && hasGoldenMarshaler(fv, seen) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for kk := 0; kk < v.Len(); kk++ {
+			if hasGoldenMarshaler(v.Index(kk), seen) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasGoldenMarshaler(iter.Value(), seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonValue converts v into values which encode like v with
+// encoding/json, replacing GoldenMarshalers with their golden form.
+func jsonValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() || isNilValue(v) && v.Kind() != reflect.Slice && v.Kind() != reflect.Map {
+		return nil, nil
+	}
+
+	if m, ok := goldenMarshaler(v.Interface()); ok {
+		data, err := m.MarshalGolden()
+		return string(data), err
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		data, err := marshalJSON(v.Interface())
+		return json.RawMessage(data), err
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return jsonValue(v.Elem())
+	case reflect.Struct:
+		var obj jsonObject
+		err := obj.addFields(v)
+		return obj, err
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		result := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			name, err := jsonKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if result[name], err = jsonValue(iter.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+		result := make([]interface{}, v.Len())
+		for kk := range result {
+			var err error
+			if result[kk], err = jsonValue(v.Index(kk)); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	return v.Interface(), nil
+}
+
+// jsonObject is a JSON object which preserves the order of its
+// fields.
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+// addFields adds the fields of the struct v as encoded by
+// encoding/json.
+func (o *jsonObject) addFields(v reflect.Value) error {
+	for _, f := range jsonFields(v.Type()) {
+		fv, ok := jsonFieldValue(v, f.index)
+		if !ok || f.omitEmpty && isEmptyJSON(fv) {
+			continue
+		}
+
+		value, err := jsonValue(fv)
+		if err == nil && f.quoted && value != nil {
+			value, err = quotedJSON(fv, value)
+		}
+		if err != nil {
+			return err
+		}
+		*o = append(*o, jsonField{f.name, value})
+	}
+	return nil
+}
+
+// jsonFieldInfo describes a struct field encoded by encoding/json.
+type jsonFieldInfo struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool
+}
+
+// jsonFields returns the fields of the struct type t which are
+// encoded by encoding/json, in the same order.  The json tags (name,
+// "-", omitempty and string) are honored and the fields of embedded
+// structs are promoted unless hidden by a less nested field or in
+// conflict with another field of the same name at the same depth.
+func jsonFields(t reflect.Type) []jsonFieldInfo {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []jsonFieldInfo
+	names := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		current := next
+		next = nil
+
+		var level []jsonFieldInfo
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for kk := 0; kk < e.typ.NumField(); kk++ {
+				f := e.typ.Field(kk)
+				ft := f.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				tag := f.Tag.Get("json")
+				if tag == "-" || f.PkgPath != "" && (!f.Anonymous || ft.Kind() != reflect.Struct) {
+					continue
+				}
+
+				name, opts := tag, ","
+				if idx := strings.Index(tag, ","); idx >= 0 {
+					name, opts = tag[:idx], tag[idx:]+","
+				}
+				if !isValidJSONTag(name) {
+					name = ""
+				}
+
+				index := append(append([]int(nil), e.index...), kk)
+				if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+
+				info := jsonFieldInfo{
+					name:      name,
+					index:     index,
+					tagged:    name != "",
+					omitEmpty: strings.Contains(opts, ",omitempty,"),
+				}
+				if name == "" {
+					info.name = f.Name
+				}
+				switch ft.Kind() {
+				case reflect.Bool, reflect.String,
+					reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+					reflect.Float32, reflect.Float64:
+					info.quoted = strings.Contains(opts, ",string,")
+				}
+				level = append(level, info)
+			}
+		}
+
+		// the fields at this depth hide the deeper fields of the
+		// same name and only survive a conflict if tagged
+		byName := map[string][]jsonFieldInfo{}
+		for _, f := range level {
+			byName[f.name] = append(byName[f.name], f)
+		}
+		for name, candidates := range byName {
+			if names[name] {
+				continue
+			}
+			names[name] = true
+
+			var tagged []jsonFieldInfo
+			for _, f := range candidates {
+				if f.tagged {
+					tagged = append(tagged, f)
+				}
+			}
+			switch {
+			case len(candidates) == 1:
+				fields = append(fields, candidates[0])
+			case len(tagged) == 1:
+				fields = append(fields, tagged[0])
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for kk := 0; kk < len(a) && kk < len(b); kk++ {
+			if a[kk] != b[kk] {
+				return a[kk] < b[kk]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// jsonFieldValue returns the field of the struct v at index.  It
+// returns false if the field is within a nil embedded pointer.
+func jsonFieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, kk := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(kk)
+	}
+	return v, true
+}
+
+func isValidJSONTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// quotedJSON encodes the value of a field with the string option as
+// a JSON string.  Fields which marshal themselves are left as is.
+func quotedJSON(fv reflect.Value, value interface{}) (interface{}, error) {
+	t := fv.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(goldenMarshalerType) || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return value, nil
+	}
+
+	data, err := marshalJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	data, err = marshalJSON(string(data))
+	return json.RawMessage(data), err
+}
+
+// jsonKey returns the name used by encoding/json for the map key k.
+func jsonKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.Type().Implements(textMarshalerType) {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		data, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(data), err
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: k.Type()}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for idx, f := range o {
+		if idx > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := marshalJSON(f.name)
+		value, err := marshalJSON(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// marshalJSON is like json.Marshal except that HTML is not escaped,
+// leaving that to the encoder of the outermost value.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func isEmptyJSON(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+type grid [][]int
+
+func (g grid) MarshalGolden() ([]byte, error) {
+	var lines []string
+	for _, row := range g {
+		lines = append(lines, strings.Trim(fmt.Sprint(row), "[]"))
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+type looseGrid struct{ grid }
+
+func (g looseGrid) DiffGolden(expected, actual []byte) (string, error) {
+	if strings.Join(strings.Fields(string(expected)), " ") == strings.Join(strings.Fields(string(actual)), " ") {
+		return "", nil
+	}
+	return "grids differ", nil
+}
+
+type badGrid struct{}
+
+func (badGrid) MarshalGolden() ([]byte, error) {
+	return nil, errors.New("bad grid")
+}
+
+type board struct {
+	Name   string  `json:"name"`
+	Grid   grid    `json:"grid"`
+	Moves  []*grid `json:"moves"`
+	Secret string  `json:"-"`
+	Note   string  `json:"note,omitempty"`
+	Embedded
+}
+
+type Embedded struct {
+	Level int
+}
+
+func TestGoldenMarshaler(t *testing.T) {
+	g := grid{{1, 2}, {3, 4}}
+	test.Artifact(t.Error, "golden_grid.txt", g)
+	test.Artifact(t.Error, "golden_grid.txt", looseGrid{grid{{1, 2}, {3, 4}}})
+	test.Artifact(t.Error, "golden_board.json", board{Name: "b", Grid: g, Moves: []*grid{&g, nil}, Secret: "x", Embedded: Embedded{2}})
+	test.Artifact(t.Error, "golden_board.dump", board{Name: "b", Grid: g}, test.Format(test.Dump))
+	test.File(t.Error, "golden_grid.json", "golden_grid.txt", func(g [][]int) grid { return g })
+}
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint("L", int(l))), nil
+}
+
+type taggedName struct {
+	Name string `json:"Name"`
+	ID   int
+}
+
+type plainName struct {
+	Name string
+	ID   int
+}
+
+type fields struct {
+	taggedName
+	plainName
+	*Embedded
+	Count  int    `json:"count,string"`
+	Text   string `json:",string"`
+	Levels map[level]string
+	HTML   string `json:"html"`
+	Prefix []interface{}
+	Extra  []interface{}
+}
+
+func TestGoldenMarshalerFields(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer os.Remove("testdata/golden_fields.json")
+
+	all := []interface{}{1, grid{{2}}}
+	v := fields{
+		taggedName: taggedName{"tagged", 1},
+		plainName:  plainName{"plain", 2},
+		Count:      3,
+		Text:       "<text>",
+		Levels:     map[level]string{4: "four"},
+		HTML:       "<b>",
+		Prefix:     all[:1],
+		Extra:      all,
+	}
+	setGoldenFlag(true)
+	test.Artifact(t.Error, "golden_fields.json", v)
+	data, err := ioutil.ReadFile("testdata/golden_fields.json")
+	check(err)
+
+	// encoding/json encodes the grid like the golden form
+	v.Extra = []interface{}{1, "2\n"}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	check(enc.Encode(v))
+	if string(data) != buf.String() {
+		t.Error("Unexpected encoding", string(data), buf.String())
+	}
+}
+
+func TestGoldenMarshalerMismatch(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args...)
+	}
+
+	test.Artifact(errorf, "golden_grid.txt", looseGrid{grid{{1, 2}, {3, 5}}})
+	test.Artifact(errorf, "golden_grid.txt", grid{{1, 2}, {3, 5}})
+	if len(failed) != 4 || failed[1] != "grids differ" || !strings.Contains(failed[3].(string), `"3 5"`) {
+		t.Error("Unexpected failures", failed)
+	}
+
+	failed = nil
+	test.Artifact(errorf, "golden_grid.txt", badGrid{})
+	test.Artifact(errorf, "golden_board.json", []interface{}{badGrid{}})
+	test.Artifact(errorf, "golden_board.dump", []interface{}{badGrid{}}, test.Format(test.Dump))
+	if len(failed) != 6 || failed[0] != "Could not marshal value" || failed[2] != "Could not marshal value" || failed[4] != "Could not marshal value" {
+		t.Error("Unexpected failures", failed)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/imports"
+)
+
+// GoSource implements comparing generated Go source against golden
+// files.
+//
+// Both the golden file and the generated source are normalized via
+// go/format and imports.Process (as with Markdown) and compared by
+// their syntax trees, so formatting changes and import reordering
+// do not cause failures.  Differences are reported as a line diff of
+// the normalized sources.
+//
+// Example Usage:
+//
+//    test.GoSource{TypeCheck: true}.Check(t.Error, "api.go", generated)
+//
+type GoSource struct {
+	// IgnoreComments excludes comments from the comparison.
+	IgnoreComments bool
+
+	// TypeCheck type-checks the golden file via go/types with
+	// imports resolved from source relative to the current
+	// directory.  The golden file must not depend on other files
+	// of its package.
+	TypeCheck bool
+}
+
+// Check compares the src against the golden file (relative to the
+// testdata/ folder of the caller).
+//
+// If the tests are run with -golden flag, the normalized src is not
+// compared but instead saved to the golden file.
+func (g GoSource) Check(errorf Errorf, goldenFile string, src []byte) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+
+	actual, err := imports.Process(goldenFile, src, nil)
+	if err != nil {
+		errorf("could not format source", err)
+		return
+	}
+
+	if *goldenFlag {
+		if err := ioutil.WriteFile(goldenFile, actual, 0644); err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	expected, err := imports.Process(goldenFile, data, nil)
+	if err != nil {
+		errorf("could not format golden file", goldenFile, err)
+		return
+	}
+
+	// the golden file as stored is type-checked, before imports
+	// are fixed up for the comparison
+	if g.TypeCheck {
+		if err := typeCheck(goldenFile, data); err != nil {
+			errorf("golden file does not type-check", goldenFile, err)
+		}
+	}
+
+	s1, err1 := g.canonical(expected)
+	s2, err2 := g.canonical(actual)
+	if err1 != nil || err2 != nil {
+		errorf("could not parse source", err1, err2)
+		return
+	}
+	if s1 != s2 {
+		errorf("unexpected output", linediff(s1, s2))
+	}
+}
+
+// canonical prints the declarations of src, listing the imports in
+// sorted order.
+func (g GoSource) canonical(src []byte) (string, error) {
+	mode := parser.ParseComments
+	if g.IgnoreComments {
+		mode = 0
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, mode)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	var imports []string
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.ImportSpec)
+				path, _ := strconv.Unquote(spec.Path.Value)
+				if spec.Name != nil {
+					path += " as " + spec.Name.Name
+				}
+				imports = append(imports, path)
+			}
+			continue
+		}
+
+		var node interface{} = decl
+		if !g.IgnoreComments {
+			node = &printer.CommentedNode{Node: decl, Comments: f.Comments}
+		}
+		buf.WriteString("\n")
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			return "", err
+		}
+		buf.WriteString("\n")
+	}
+	sort.Strings(imports)
+
+	header := "package " + f.Name.Name + "\n"
+	for _, path := range imports {
+		header += "\nimport " + path
+	}
+	return header + "\n" + buf.String(), nil
+}
+
+func typeCheck(name string, src []byte) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		return err
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	return err
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+const generated = `package hello
+
+import "strings"
+import "fmt"
+
+// Hello greets the name.
+func Hello(name string) string {
+	return fmt.Sprint("hello ", strings.ToUpper(name))
+}
+`
+
+func TestGoSource(t *testing.T) {
+	test.GoSource{TypeCheck: true}.Check(t.Error, "gosource_hello.go", []byte(generated))
+}
+
+func TestGoSourceNormalized(t *testing.T) {
+	src := strings.Replace(generated, "\treturn", "        return", 1)
+	src = strings.Replace(src, `import "strings"
+import "fmt"`, `import (
+	"fmt"
+
+	"strings"
+)`, 1)
+	test.GoSource{}.Check(t.Error, "gosource_hello.go", []byte(src))
+
+	src = strings.Replace(src, "greets", "welcomes", 1)
+	test.GoSource{IgnoreComments: true}.Check(t.Error, "gosource_hello.go", []byte(src))
+
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+	test.GoSource{}.Check(errorf, "gosource_hello.go", []byte(src))
+	if len(failed) != 2 || failed[0] != "unexpected output" || !strings.Contains(failed[1].(string), "welcomes") {
+		t.Error("Unexpected failure", failed)
+	}
+}
+
+func TestGoSourceErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.GoSource{}.Check(errorf, "gosource_hello.go", []byte("package"))
+	test.GoSource{}.Check(errorf, "non-existent.go", []byte(generated))
+	test.GoSource{}.Check(errorf, "input.txt", []byte(generated))
+	test.GoSource{TypeCheck: true}.Check(errorf, "gosource_stale.go", []byte(generated))
+	test.GoSource{TypeCheck: true}.Check(errorf, "gosource_noimport.go", []byte(generated))
+
+	expected := []interface{}{
+		"could not format source",
+		"error reading",
+		"could not format golden file",
+		"golden file does not type-check",
+		"unexpected output",
+		"golden file does not type-check",
+	}
+	if len(failed) != len(expected) {
+		t.Fatal("Unexpected failures", failed)
+	}
+	for idx := range expected {
+		if failed[idx] != expected[idx] {
+			t.Error("Unexpected failure", idx, failed[idx])
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+)
+
+// volatileHeaders are the response headers whose values are replaced
+// with "*" by Handler.
+var volatileHeaders = []string{
+	"Date", "Content-Length", "X-Request-Id", "X-Trace-Id",
+	"X-Correlation-Id", "Traceparent", "Tracestate", "X-B3-Traceid",
+	"X-B3-Spanid", "X-Cloud-Trace-Context",
+}
+
+// Handler implements testing an http.Handler against raw HTTP files.
+//
+// The inputFile and outputFile are relative to the testdata/ folder
+// of the caller.  The input is a raw HTTP/1.1 request: the request
+// line, headers, a blank line and the body.  It is served through the
+// handler via httptest and the response is compared against the
+// output file, which holds the raw response (status line, headers
+// sorted by name, a blank line and the body).
+//
+// The values of volatile headers such as Date, Content-Length and
+// common trace ID headers, along with any headers listed in ignore,
+// are replaced with "*".  JSON response bodies are stored indented
+// and compared structurally; other bodies are compared line by line.
+//
+// If the tests are run with -golden flag, the output is not compared
+// but instead the output files are generated.
+//
+// Example Usage:
+//
+//    test.Handler(t.Error, "get_user.http", "get_user.golden.http", mux)
+//
+func Handler(errorf Errorf, inputFile, outputFile string, h http.Handler, ignore ...string) {
+	dir := callerTestdata(2)
+	inputFile = filepath.Join(dir, inputFile)
+	outputFile = filepath.Join(dir, outputFile)
+
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		errorf("error reading", inputFile, err)
+		return
+	}
+
+	req, err := parseRequest(data)
+	if err != nil {
+		errorf("could not parse request", inputFile, err)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	resp := w.Result()
+
+	for _, name := range append(volatileHeaders, ignore...) {
+		if _, ok := resp.Header[http.CanonicalHeaderKey(name)]; ok {
+			resp.Header.Set(name, "*")
+		}
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	isJSON := isJSONContent(resp.Header.Get("Content-Type"))
+	if isJSON {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "  "); err == nil {
+			body = append(buf.Bytes(), '\n')
+		}
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "HTTP/1.1 %03d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	for _, key := range sortedHeaderKeys(resp.Header) {
+		for _, v := range resp.Header[key] {
+			fmt.Fprintf(&head, "%s: %s\n", key, v)
+		}
+	}
+	head.WriteString("\n")
+
+	if *goldenFlag {
+		output := append(head.Bytes(), body...)
+		if err := ioutil.WriteFile(outputFile, output, 0644); err != nil {
+			errorf("Could not save golden output", outputFile, err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		errorf("error reading", outputFile, err)
+		return
+	}
+
+	expectedHead, expectedBody := splitMessage(golden)
+	if string(expectedHead)+"\n" != head.String() {
+		errorf("unexpected response headers", linediff(string(expectedHead)+"\n", head.String()))
+	}
+
+	if isJSON {
+		diff, err := jsonDiff(expectedBody, body)
+		if err != nil || diff != "" {
+			errorf("unexpected response body", diff, err)
+		}
+	} else if string(expectedBody) != string(body) {
+		errorf("unexpected response body", linediff(string(expectedBody), string(body)))
+	}
+}
+
+// parseRequest parses a raw HTTP request.  The body is everything
+// after the first blank line, irrespective of Content-Length.
+func parseRequest(data []byte) (*http.Request, error) {
+	head, body := splitMessage(data)
+	if head == nil {
+		head = data
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(append(head, "\n\n"...))))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.RemoteAddr = "192.0.2.1:1234"
+	if req.URL.Host == "" {
+		req.URL.Host = req.Host
+	}
+	return req, nil
+}
+
+// splitMessage splits a raw HTTP message into its head and body at
+// the first blank line.  The returned head excludes the blank line
+// and uses \n line endings.  It is nil if there is no blank line.
+func splitMessage(data []byte) (head, body []byte) {
+	for idx := 0; idx < len(data); idx++ {
+		if data[idx] != '\n' {
+			continue
+		}
+		rest := data[idx+1:]
+		if bytes.HasPrefix(rest, []byte("\r\n")) {
+			rest = rest[2:]
+		} else if bytes.HasPrefix(rest, []byte("\n")) {
+			rest = rest[1:]
+		} else {
+			continue
+		}
+		head = bytes.Replace(data[:idx+1], []byte("\r\n"), []byte("\n"), -1)
+		return head, rest
+	}
+	return nil, nil
+}
+
+func isJSONContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+func sortedHeaderKeys(h http.Header) []string {
+	m := make(map[string]string, len(h))
+	for key := range h {
+		m[key] = key
+	}
+	return sortedKeys(m)
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tvastar/test"
+)
+
+var mux = func() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		var user map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		user["id"] = 42
+		user["verbose"] = r.URL.Query().Get("verbose")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", fmt.Sprint(time.Now().UnixNano()))
+		w.Header().Set("Date", time.Now().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+		check(json.NewEncoder(w).Encode(user))
+	})
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Session", fmt.Sprint(time.Now().UnixNano()))
+		fmt.Fprintf(w, "hello %s\nfrom %s\n", r.Header.Get("Accept"), r.Host)
+	})
+	return mux
+}()
+
+func TestHandler(t *testing.T) {
+	test.Handler(t.Error, "handler_post.http", "handler_post.golden.http", mux)
+	test.Handler(t.Error, "handler_get.http", "handler_get.golden.http", mux, "X-Session")
+}
+
+func TestHandlerJSONStructural(t *testing.T) {
+	compact := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var user map[string]interface{}
+		check(json.Unmarshal(body, &user))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"verbose":"1","tags":["a","b"],"name":"alice","id":42}`)
+	})
+
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+	test.Handler(errorf, "handler_post.http", "handler_post.golden.http", compact)
+	if len(reports) != 1 || reports[0] != "unexpected response headers" {
+		t.Error("Unexpected reports", reports)
+	}
+}
+
+func TestHandlerFailures(t *testing.T) {
+	var reports []string
+	errorf := func(args ...interface{}) {
+		reports = append(reports, fmt.Sprint(args[0]))
+	}
+
+	test.Handler(errorf, "non-existent", "non-existent", mux)
+	test.Handler(errorf, "input.json", "non-existent", mux)
+	test.Handler(errorf, "handler_get.http", "non-existent", mux)
+	test.Handler(errorf, "handler_get.http", "handler_post.golden.http", mux)
+
+	expected := []string{
+		"error reading",
+		"could not parse request",
+		"error reading",
+		"unexpected response headers",
+		"unexpected response body",
+	}
+	if strings.Join(reports, "\n") != strings.Join(expected, "\n") {
+		t.Error("Unexpected reports", reports)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Image implements comparing images against PNG golden files.
+//
+// Two pixels match if none of their 8-bit red, green, blue and alpha
+// channels differ by more than Tolerance.  The images match if they
+// have the same size and the fraction of mismatched pixels does not
+// exceed MaxDiffRatio.
+//
+// Example Usage:
+//
+//    test.Image{Tolerance: 2, MaxDiffRatio: 0.001}.
+//        Check(t.Error, "chart.png", renderChart(data))
+//
+type Image struct {
+	// Tolerance is the maximum difference allowed per channel.
+	Tolerance uint8
+
+	// MaxDiffRatio is the maximum fraction of pixels allowed to
+	// differ beyond Tolerance.
+	MaxDiffRatio float64
+}
+
+// Check compares img against the PNG golden file (relative to the
+// testdata/ folder of the caller).
+//
+// On a mismatch, the expected, actual and diff images are written
+// next to the golden file with the .expected.png, .actual.png and
+// .diff.png suffixes.  The diff image shows the expected image faded
+// with the mismatched pixels in red.  These files are removed when
+// the images match.
+//
+// If the tests are run with -golden flag, the image is not compared
+// but instead saved to the golden file.
+func (i Image) Check(errorf Errorf, goldenFile string, img image.Image) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+	base := strings.TrimSuffix(goldenFile, filepath.Ext(goldenFile))
+	expectedFile, actualFile, diffFile := base+".expected.png", base+".actual.png", base+".diff.png"
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		errorf("Could not encode image", err)
+		return
+	}
+
+	if *goldenFlag {
+		if err := ioutil.WriteFile(goldenFile, buf.Bytes(), 0644); err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	expected, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		errorf("could not decode golden image", goldenFile, err)
+		return
+	}
+
+	var msg string
+	diff, count := i.diff(expected, img)
+	eb, ab := expected.Bounds(), img.Bounds()
+	total := ab.Dx() * ab.Dy()
+	switch {
+	case eb.Dx() != ab.Dx() || eb.Dy() != ab.Dy():
+		msg = fmt.Sprintf("unexpected image size: expected %dx%d, got %dx%d", eb.Dx(), eb.Dy(), ab.Dx(), ab.Dy())
+	case float64(count) > i.MaxDiffRatio*float64(total):
+		msg = fmt.Sprintf("unexpected image: %d of %d pixels differ", count, total)
+	default:
+		for _, name := range []string{expectedFile, actualFile, diffFile} {
+			_ = os.Remove(name)
+		}
+		return
+	}
+
+	var diffBuf bytes.Buffer
+	err = png.Encode(&diffBuf, diff)
+	if err == nil {
+		err = ioutil.WriteFile(expectedFile, data, 0644)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(actualFile, buf.Bytes(), 0644)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(diffFile, diffBuf.Bytes(), 0644)
+	}
+	if err != nil {
+		errorf(msg, err)
+		return
+	}
+	errorf(msg, "see", diffFile)
+}
+
+// diff returns an image highlighting the mismatched pixels along
+// with their count.  The images are aligned at their top-left corner.
+func (i Image) diff(expected, actual image.Image) (*image.NRGBA, int) {
+	eb, ab := expected.Bounds(), actual.Bounds()
+	w, h := eb.Dx(), eb.Dy()
+	if ab.Dx() > w {
+		w = ab.Dx()
+	}
+	if ab.Dy() > h {
+		h = ab.Dy()
+	}
+
+	result := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(result, result.Bounds(), image.White, image.Point{}, draw.Src)
+
+	red := color.NRGBA{R: 255, A: 255}
+	count := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			ep, ap := image.Pt(eb.Min.X+x, eb.Min.Y+y), image.Pt(ab.Min.X+x, ab.Min.Y+y)
+			if !ep.In(eb) || !ap.In(ab) {
+				result.Set(x, y, red)
+				continue
+			}
+
+			c1, c2 := expected.At(ep.X, ep.Y), actual.At(ap.X, ap.Y)
+			if !i.match(c1, c2) {
+				result.Set(x, y, red)
+				count++
+				continue
+			}
+
+			gray := color.GrayModel.Convert(c1).(color.Gray)
+			result.Set(x, y, color.NRGBA{gray.Y, gray.Y, gray.Y, 64})
+		}
+	}
+	return result, count
+}
+
+func (i Image) match(c1, c2 color.Color) bool {
+	n1 := color.NRGBAModel.Convert(c1).(color.NRGBA)
+	n2 := color.NRGBAModel.Convert(c2).(color.NRGBA)
+	within := func(a, b uint8) bool {
+		if a > b {
+			a, b = b, a
+		}
+		return b-a <= i.Tolerance
+	}
+	return within(n1.R, n2.R) && within(n1.G, n2.G) && within(n1.B, n2.B) && within(n1.A, n2.A)
+}
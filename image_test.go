@@ -0,0 +1,80 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func gradient() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x * 8), uint8(y * 16), 128, 255})
+		}
+	}
+	return img
+}
+
+func TestImage(t *testing.T) {
+	test.Image{}.Check(t.Error, "image_gradient.png", gradient())
+}
+
+func TestImageTolerance(t *testing.T) {
+	img := gradient()
+	img.Set(0, 0, color.NRGBA{2, 0, 126, 255})
+	test.Image{Tolerance: 2}.Check(t.Error, "image_gradient.png", img)
+
+	img.Set(1, 1, color.NRGBA{255, 255, 255, 255})
+	test.Image{Tolerance: 2, MaxDiffRatio: 0.01}.Check(t.Error, "image_gradient.png", img)
+}
+
+func TestImageMismatch(t *testing.T) {
+	defer func() {
+		for _, suffix := range []string{".expected.png", ".actual.png", ".diff.png"} {
+			if err := os.Remove("testdata/image_gradient" + suffix); err != nil {
+				t.Error("Missing output", suffix, err)
+			}
+		}
+	}()
+
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+
+	img := gradient()
+	img.Set(1, 1, color.NRGBA{255, 255, 255, 255})
+	img.Set(2, 1, color.NRGBA{255, 255, 255, 255})
+	test.Image{MaxDiffRatio: 0.001}.Check(errorf, "image_gradient.png", img)
+	if len(failed) == 0 || failed[0] != "unexpected image: 2 of 512 pixels differ" {
+		t.Error("Unexpected failure", failed)
+	}
+
+	failed = nil
+	test.Image{}.Check(errorf, "image_gradient.png", image.NewNRGBA(image.Rect(0, 0, 16, 16)))
+	if len(failed) == 0 || !strings.HasPrefix(failed[0].(string), "unexpected image size") {
+		t.Error("Unexpected failure", failed)
+	}
+}
+
+func TestImageErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.Image{}.Check(errorf, "non-existent.png", gradient())
+	test.Image{}.Check(errorf, "input.txt", gradient())
+	if len(failed) != 2 || failed[0] != "error reading" || failed[1] != "could not decode golden image" {
+		t.Error("Unexpected failures", failed)
+	}
+}
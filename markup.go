@@ -0,0 +1,390 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// HTML implements comparing HTML output against a golden file
+// (relative to the testdata/ folder of the caller).
+//
+// Both sides are parsed like a browser would (void elements such as
+// <br> need not be closed, optional end tags may be omitted and the
+// contents of <script> and <style> are raw text) and normalized:
+// attributes are sorted, runs of whitespace in text are collapsed
+// and whitespace only text is dropped (except within <pre>,
+// <textarea>, <script> and <style>) and comments and the doctype are
+// dropped.
+//
+// Differences are reported as element paths such as
+// html>body>div[2]@class where [2] is the position of the element
+// among its siblings of the same name.
+//
+// If the tests are run with -golden flag, the output is not compared
+// but instead the canonical pretty printed form is saved.
+//
+// Example Usage:
+//
+//    test.HTML(t.Error, "index.html", renderIndex())
+//
+func HTML(errorf Errorf, goldenFile, src string) {
+	markup(errorf, filepath.Join(callerTestdata(2), goldenFile), src, true)
+}
+
+// XML implements comparing XML output against a golden file
+// (relative to the testdata/ folder of the caller).  It is like HTML
+// except that the input must be well-formed, self-closing tags are
+// treated like empty elements and namespace prefixes are compared as
+// written.
+func XML(errorf Errorf, goldenFile, src string) {
+	markup(errorf, filepath.Join(callerTestdata(2), goldenFile), src, false)
+}
+
+func markup(errorf Errorf, goldenFile, src string, isHTML bool) {
+	actual, err := parseMarkup(src, isHTML)
+	if err != nil {
+		errorf("could not parse output", err)
+		return
+	}
+
+	if *goldenFlag {
+		if err := ioutil.WriteFile(goldenFile, []byte(actual.format(isHTML)), 0644); err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	expected, err := parseMarkup(string(data), isHTML)
+	if err != nil {
+		errorf("could not parse golden file", goldenFile, err)
+		return
+	}
+
+	if diffs := expected.diff(actual, ""); len(diffs) > 0 {
+		errorf("unexpected output", strings.Join(diffs, "\n"))
+	}
+}
+
+// markupNode is an element or, if name is empty, a text node.  The
+// root of a document is an element with an empty name.
+type markupNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*markupNode
+	text     string
+
+	// preserve is set for HTML elements whose whitespace is
+	// significant, such as <pre> and <script>
+	preserve bool
+}
+
+func parseMarkup(src string, isHTML bool) (*markupNode, error) {
+	if isHTML {
+		return parseHTML(src)
+	}
+
+	d := xml.NewDecoder(strings.NewReader(src))
+	root := &markupNode{}
+	stack := []*markupNode{root}
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		top := stack[len(stack)-1]
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &markupNode{name: markupName(tok.Name)}
+			for _, attr := range tok.Attr {
+				attr.Name = xml.Name{Local: markupName(attr.Name)}
+				n.attrs = append(n.attrs, attr)
+			}
+			n.sortAttrs()
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if name := markupName(tok.Name); top.name != name || len(stack) == 1 {
+				return nil, fmt.Errorf("unexpected end element </%s>", name)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top.addText(string(tok), false)
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed element <%s>", stack[len(stack)-1].name)
+	}
+	return root, nil
+}
+
+// parseHTML parses src with the HTML5 parsing algorithm, so implied
+// elements (such as <head> and <body>) are always present.
+func parseHTML(src string) (*markupNode, error) {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	root := &markupNode{}
+	root.addHTML(doc, false)
+	return root, nil
+}
+
+// addHTML appends the converted children of node to n.
+func (n *markupNode) addHTML(node *html.Node, preserve bool) {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			child := &markupNode{name: c.Data}
+			for _, attr := range c.Attr {
+				name := attr.Key
+				if attr.Namespace != "" {
+					name = attr.Namespace + ":" + name
+				}
+				child.attrs = append(child.attrs, xml.Attr{Name: xml.Name{Local: name}, Value: attr.Val})
+			}
+			child.sortAttrs()
+			child.preserve = preserve || c.Data == "pre" || c.Data == "textarea" || isRawTextElement(c.Data)
+			n.children = append(n.children, child)
+			child.addHTML(c, child.preserve)
+		case html.TextNode:
+			n.addText(c.Data, preserve)
+		}
+	}
+}
+
+func (n *markupNode) sortAttrs() {
+	sort.Slice(n.attrs, func(i, j int) bool {
+		return n.attrs[i].Name.Local < n.attrs[j].Name.Local
+	})
+}
+
+// addText appends text to n, merging it with a preceding text node.
+// Unless preserve is set, runs of whitespace are collapsed and
+// whitespace only text is dropped.
+func (n *markupNode) addText(text string, preserve bool) {
+	if !preserve {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	if text == "" {
+		return
+	}
+	if last := len(n.children) - 1; last >= 0 && n.children[last].name == "" {
+		n.children[last].text += text
+		return
+	}
+	n.children = append(n.children, &markupNode{text: text})
+}
+
+func markupName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func isVoidElement(name string) bool {
+	for _, void := range xml.HTMLAutoClose {
+		if name == void {
+			return true
+		}
+	}
+	return false
+}
+
+// isRawTextElement reports whether the text of the HTML element is
+// not escaped, as with <script> and <style>.
+func isRawTextElement(name string) bool {
+	switch name {
+	case "script", "style", "xmp", "iframe", "noembed", "noframes", "noscript", "plaintext":
+		return true
+	}
+	return false
+}
+
+// format pretty prints the node with two space indentation.
+func (n *markupNode) format(isHTML bool) string {
+	var buf bytes.Buffer
+	for _, child := range n.children {
+		child.write(&buf, "", isHTML)
+	}
+	return buf.String()
+}
+
+func (n *markupNode) write(buf *bytes.Buffer, indent string, isHTML bool) {
+	if n.name == "" {
+		buf.WriteString(indent)
+		_ = xml.EscapeText(buf, []byte(n.text))
+		buf.WriteString("\n")
+		return
+	}
+
+	buf.WriteString(indent)
+	if n.preserve {
+		n.writeInline(buf, false)
+		buf.WriteString("\n")
+		return
+	}
+	n.writeStart(buf)
+
+	switch {
+	case isHTML && isVoidElement(n.name):
+		buf.WriteString(">\n")
+	case len(n.children) == 0 && !isHTML:
+		buf.WriteString("/>\n")
+	case len(n.children) == 0:
+		buf.WriteString("></" + n.name + ">\n")
+	default:
+		buf.WriteString(">\n")
+		for _, child := range n.children {
+			child.write(buf, indent+"  ", isHTML)
+		}
+		buf.WriteString(indent + "</" + n.name + ">\n")
+	}
+}
+
+// writeInline writes the HTML node without adding any whitespace.
+// The text of raw text elements (such as <script>) is not escaped.
+func (n *markupNode) writeInline(buf *bytes.Buffer, raw bool) {
+	if n.name == "" {
+		if raw {
+			buf.WriteString(n.text)
+		} else {
+			buf.WriteString(html.EscapeString(n.text))
+		}
+		return
+	}
+
+	n.writeStart(buf)
+	buf.WriteString(">")
+	if isVoidElement(n.name) {
+		return
+	}
+	// the parser drops a newline right after these start tags
+	if len(n.children) > 0 && strings.HasPrefix(n.children[0].text, "\n") {
+		switch n.name {
+		case "pre", "textarea", "listing":
+			buf.WriteString("\n")
+		}
+	}
+	for _, child := range n.children {
+		child.writeInline(buf, isRawTextElement(n.name))
+	}
+	buf.WriteString("</" + n.name + ">")
+}
+
+func (n *markupNode) writeStart(buf *bytes.Buffer) {
+	buf.WriteString("<" + n.name)
+	for _, attr := range n.attrs {
+		buf.WriteString(" " + attr.Name.Local + `="`)
+		_ = xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteString(`"`)
+	}
+}
+
+// diff compares the children of n and other, returning the
+// differences by element path.
+func (n *markupNode) diff(other *markupNode, path string) []string {
+	var diffs []string
+	paths := n.childPaths(path)
+	otherPaths := other.childPaths(path)
+
+	for idx := 0; idx < len(n.children) || idx < len(other.children); idx++ {
+		switch {
+		case idx >= len(other.children):
+			diffs = append(diffs, paths[idx]+": missing")
+		case idx >= len(n.children):
+			diffs = append(diffs, otherPaths[idx]+": unexpected")
+		case n.children[idx].name != other.children[idx].name:
+			diffs = append(diffs, fmt.Sprintf("%s: expected %s, got %s", paths[idx], n.children[idx].describe(), other.children[idx].describe()))
+		case n.children[idx].name == "":
+			if n.children[idx].text != other.children[idx].text {
+				diffs = append(diffs, fmt.Sprintf("%s: expected %q, got %q", paths[idx], n.children[idx].text, other.children[idx].text))
+			}
+		default:
+			diffs = append(diffs, n.children[idx].diffAttrs(other.children[idx], paths[idx])...)
+			diffs = append(diffs, n.children[idx].diff(other.children[idx], paths[idx])...)
+		}
+	}
+	return diffs
+}
+
+func (n *markupNode) diffAttrs(other *markupNode, path string) []string {
+	values := map[string]string{}
+	for _, attr := range other.attrs {
+		values[attr.Name.Local] = attr.Value
+	}
+
+	var diffs []string
+	for _, attr := range n.attrs {
+		v, ok := values[attr.Name.Local]
+		delete(values, attr.Name.Local)
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s@%s: missing", path, attr.Name.Local))
+		case v != attr.Value:
+			diffs = append(diffs, fmt.Sprintf("%s@%s: expected %q, got %q", path, attr.Name.Local, attr.Value, v))
+		}
+	}
+	for _, name := range sortedKeys(values) {
+		diffs = append(diffs, fmt.Sprintf("%s@%s: unexpected", path, name))
+	}
+	return diffs
+}
+
+// childPaths returns the paths of the children of n.  Elements are
+// indexed among their siblings of the same name if there are several
+// of them.  Text nodes are named #text.
+func (n *markupNode) childPaths(path string) []string {
+	counts := map[string]int{}
+	for _, child := range n.children {
+		counts[child.name]++
+	}
+
+	seen := map[string]int{}
+	result := make([]string, len(n.children))
+	for idx, child := range n.children {
+		name := child.name
+		if name == "" {
+			name = "#text"
+		}
+		seen[child.name]++
+		if counts[child.name] > 1 {
+			name += fmt.Sprintf("[%d]", seen[child.name])
+		}
+		if path != "" {
+			name = path + ">" + name
+		}
+		result[idx] = name
+	}
+	return result
+}
+
+func (n *markupNode) describe() string {
+	if n.name == "" {
+		return fmt.Sprintf("text %q", n.text)
+	}
+	return "<" + n.name + ">"
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+const page = `<!DOCTYPE html>
+<html><head><title>Hello  &amp; welcome</title></head>
+<body>
+  <!-- navigation -->
+  <div id="nav" class="menu"><a href="/">Home</a><br/></div>
+  <div class="content">
+    <p>Some   text<br>more text</p>
+    <input type="checkbox" checked>
+  </div>
+</body></html>`
+
+const feed = `<?xml version="1.0"?>
+<feed xmlns:media="http://search.yahoo.com/mrss/">
+  <entry id="1"><title>First</title><media:thumbnail url="a.png"/></entry>
+  <entry id="2"><title>Second</title></entry>
+</feed>`
+
+func TestHTML(t *testing.T) {
+	test.HTML(t.Error, "markup_page.html", page)
+	test.XML(t.Error, "markup_feed.xml", feed)
+}
+
+func TestHTMLNormalized(t *testing.T) {
+	src := strings.Replace(page, `id="nav" class="menu"`, "class='menu'\n   id=nav", 1)
+	src = strings.Replace(src, "<br/>", "<BR>", 1)
+	test.HTML(t.Error, "markup_page.html", src)
+
+	src = strings.Replace(feed, `<media:thumbnail url="a.png"/>`, `<media:thumbnail url="a.png"></media:thumbnail>`, 1)
+	test.XML(t.Error, "markup_feed.xml", src)
+}
+
+const rawText = `<html><head>
+<style>p > a { color: red }</style>
+<script>// hi
+if (a < b && c) { document.write("</p>") }</script>
+</head><body><p class=intro>one<p>two
+<pre>  a  <b>b</b>
+ c</pre><textarea>
+
+x  y</textarea></body></html>`
+
+func TestHTMLRawText(t *testing.T) {
+	test.HTML(t.Error, "markup_script.html", rawText)
+
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+	src := strings.Replace(rawText, "// hi\n", "// hi ", 1)
+	test.HTML(errorf, "markup_script.html", src)
+
+	expected := `html>head>script>#text: expected "// hi\nif (a < b && c) { document.write(\"</p>\") }", got "// hi if (a < b && c) { document.write(\"</p>\") }"`
+	if len(failed) != 2 || failed[0] != "unexpected output" || failed[1] != expected {
+		t.Error("Unexpected failure", failed)
+	}
+}
+
+func TestHTMLGoldenRoundTrip(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer os.Remove("testdata/markup_roundtrip.html")
+
+	setGoldenFlag(true)
+	test.HTML(t.Error, "markup_roundtrip.html", rawText)
+	setGoldenFlag(false)
+	test.HTML(t.Error, "markup_roundtrip.html", rawText)
+}
+
+func TestHTMLMismatch(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+
+	src := strings.Replace(page, `class="content"`, `class="main"`, 1)
+	src = strings.Replace(src, "more text", "other text", 1)
+	src = strings.Replace(src, `<a href="/">Home</a>`, "", 1)
+	test.HTML(errorf, "markup_page.html", src)
+
+	expected := strings.Join([]string{
+		`html>body>div[1]>a: expected <a>, got <br>`,
+		`html>body>div[1]>br: missing`,
+		`html>body>div[2]@class: expected "content", got "main"`,
+		`html>body>div[2]>p>#text[2]: expected "more text", got "other text"`,
+	}, "\n")
+	if len(failed) != 2 || failed[0] != "unexpected output" || failed[1] != expected {
+		t.Error("Unexpected failure", failed)
+	}
+
+	failed = nil
+	src = strings.Replace(feed, `<entry id="2">`, `<entry id="2" lang="en">`, 1)
+	src = strings.Replace(src, `<title>Second</title>`, `<title>Second</title><title>Again</title>`, 1)
+	test.XML(errorf, "markup_feed.xml", src)
+
+	expected = strings.Join([]string{
+		`feed>entry[2]@lang: unexpected`,
+		`feed>entry[2]>title[2]: unexpected`,
+	}, "\n")
+	if len(failed) != 2 || failed[0] != "unexpected output" || failed[1] != expected {
+		t.Error("Unexpected failure", failed)
+	}
+}
+
+func TestHTMLErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.XML(errorf, "markup_feed.xml", "<feed><entry></feed>")
+	test.XML(errorf, "markup_feed.xml", "<feed><entry>")
+	test.XML(errorf, "non-existent.xml", feed)
+	test.XML(errorf, "markup_page.html", feed)
+
+	expected := []interface{}{
+		"could not parse output",
+		"could not parse output",
+		"error reading",
+		"could not parse golden file",
+	}
+	if len(failed) != len(expected) {
+		t.Fatal("Unexpected failures", failed)
+	}
+	for idx := range expected {
+		if failed[idx] != expected[idx] {
+			t.Error("Unexpected failure", idx, failed[idx])
+		}
+	}
+}
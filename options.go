@@ -0,0 +1,183 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// Option configures the behavior of File and Artifact.
+type Option func(o *options)
+
+type options struct {
+	repeat     int
+	procs      []int
+	shuffle    bool
+	codec      Codec
+	objectSize int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{repeat: *repeatFlag, codec: JSON}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Repeat checks that the output is deterministic by producing it n
+// times and failing with a diff between the runs if they do not all
+// match.  This check happens before the output is compared against
+// (or written as) the golden file.
+//
+// For File, the function is invoked n times.  For Artifact, values
+// which are functions are invoked n times and the result is encoded
+// each time.
+//
+// The -repeat flag enables this for all calls.
+func Repeat(n int) Option {
+	return func(o *options) {
+		if n > o.repeat {
+			o.repeat = n
+		}
+	}
+}
+
+// Procs sets GOMAXPROCS to each of the provided values in turn for
+// the repeated runs (see Repeat).  The original value is restored
+// afterwards.
+func Procs(procs ...int) Option {
+	return func(o *options) {
+		o.procs = procs
+	}
+}
+
+// Shuffle reorders the keys of JSON objects in File inputs for every
+// repeated run after the first (see Repeat).  Inputs passed as
+// string, []byte or []rune and inputs that are not JSON are passed as
+// is.
+func Shuffle() Option {
+	return func(o *options) {
+		o.shuffle = true
+	}
+}
+
+// Objects stores golden outputs of at least minSize bytes in a
+// content-addressed object directory (testdata/.objects) with the
+// golden file only holding a small pointer to the object:
+//
+//    sha256:5d41402abc4b2a76b9719d911017c592... 31457280
+//
+// Identical outputs share a single object.  Pointers are resolved
+// transparently when golden files are read, and golden files which
+// already are pointers remain so when they are regenerated even
+// without this option.  Objects which are no longer referenced can be
+// found with UnusedObjects and removed with cmd/testgolden.
+//
+// The object is compressed if the golden file has a .gz or .zst
+// extension while the pointer itself is always plain text.
+func Objects(minSize int) Option {
+	return func(o *options) {
+		o.objectSize = minSize
+	}
+}
+
+// stable calls produce as configured and returns its output.
+// Differences between the runs are reported via errorf while produce
+// is expected to report its own errors.
+func (o *options) stable(errorf Errorf, produce func(run int) (string, bool)) (string, bool) {
+	runs := o.repeat
+	if runs < 1 {
+		runs = 1
+	}
+
+	if len(o.procs) > 0 {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	}
+
+	var first string
+	for run := 0; run < runs; run++ {
+		if len(o.procs) > 0 {
+			runtime.GOMAXPROCS(o.procs[run%len(o.procs)])
+		}
+
+		output, ok := produce(run)
+		if !ok {
+			return "", false
+		}
+
+		if run == 0 {
+			first = output
+		} else if output != first {
+			msg := fmt.Sprintf("output is not deterministic (run 1 vs run %d)", run+1)
+			errorf(msg, linediff(first, output))
+			return "", false
+		}
+	}
+	return first, true
+}
+
+// input returns the input to use for the provided run.  Only inputs
+// which are JSON decoded into argType are shuffled.
+func (o *options) input(run int, input string, argType reflect.Type) string {
+	if !o.shuffle || run == 0 || isText(argType) {
+		return input
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader([]byte(input)))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return input
+	}
+
+	var buf bytes.Buffer
+	writeShuffled(&buf, v, rand.New(rand.NewSource(int64(run))))
+	return buf.String()
+}
+
+func writeShuffled(buf *bytes.Buffer, v interface{}, r *rand.Rand) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		r.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+
+		buf.WriteString("{")
+		for idx, key := range keys {
+			if idx > 0 {
+				buf.WriteString(",")
+			}
+			writeShuffled(buf, key, r)
+			buf.WriteString(":")
+			writeShuffled(buf, v[key], r)
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for idx, elt := range v {
+			if idx > 0 {
+				buf.WriteString(",")
+			}
+			writeShuffled(buf, elt, r)
+		}
+		buf.WriteString("]")
+	default:
+		bytes, _ := json.Marshal(v)
+		buf.Write(bytes)
+	}
+}
+
+var repeatFlag = flag.Int("repeat", 0, "invoke golden tested functions this many times to check that their output is deterministic")
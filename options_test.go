@@ -0,0 +1,114 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"errors"
+	"flag"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func unstable() func(input string) string {
+	count := 0
+	return func(input string) string {
+		count++
+		return input + strconv.Itoa(count)
+	}
+}
+
+func TestRepeatStable(t *testing.T) {
+	test.File(t.Error, "input.txt", "output.txt", identity, test.Repeat(5))
+	test.File(t.Error, "cases/hello.in.txt", "cases/hello.out.txt", shout, test.Repeat(2), test.Procs(1, 2))
+
+	id := func(input struct{ OK int }) struct{ OK int } {
+		return input
+	}
+	test.File(t.Error, "input.json", "output.json", id, test.Repeat(5), test.Shuffle())
+
+	// raw text inputs are passed through unchanged
+	test.File(t.Error, "input.json", "input.json", identity, test.Repeat(3), test.Shuffle())
+	test.File(t.Error, "input.json", "input.json", func(input []byte) []byte {
+		return input
+	}, test.Repeat(3), test.Shuffle())
+}
+
+func TestRepeatUnstable(t *testing.T) {
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+
+	test.File(errorf, "input.txt", "output.txt", unstable(), test.Repeat(3))
+	test.File(errorf, "input.txt", "output.txt", unstable())
+
+	procs := func(input string) string {
+		return strconv.Itoa(runtime.GOMAXPROCS(0))
+	}
+	before := runtime.GOMAXPROCS(0)
+	test.File(errorf, "input.txt", "output.txt", procs, test.Repeat(2), test.Procs(before, before+1))
+	if runtime.GOMAXPROCS(0) != before {
+		t.Error("GOMAXPROCS was not restored")
+	}
+
+	expected := []interface{}{
+		"output is not deterministic (run 1 vs run 2)",
+		"unexpected output",
+		"output is not deterministic (run 1 vs run 2)",
+	}
+	if len(reports) != len(expected) {
+		t.Fatal("Unexpected reports", reports)
+	}
+	for kk := range expected {
+		if reports[kk] != expected[kk] {
+			t.Error("Unexpected report", kk, reports[kk])
+		}
+	}
+}
+
+func TestRepeatFlag(t *testing.T) {
+	defer restoreFlag("repeat")()
+	check(flag.Set("repeat", "2"))
+
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+	test.File(errorf, "input.txt", "output.txt", unstable())
+	if len(reports) != 1 || !strings.Contains(reports[0].(string), "not deterministic") {
+		t.Error("Unexpected reports", reports)
+	}
+}
+
+func TestArtifactFunction(t *testing.T) {
+	value := func() interface{} {
+		return map[string]interface{}{"OK": 5}
+	}
+	test.Artifact(t.Error, "output.json", value, test.Repeat(3))
+
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+
+	count := 0
+	test.Artifact(errorf, "output.json", func() (int, error) {
+		count++
+		return count, nil
+	}, test.Repeat(3))
+
+	failure := errors.New("failure")
+	test.Artifact(errorf, "output.json", func() (int, error) {
+		return 0, failure
+	})
+
+	if len(reports) != 2 || reports[0] != "output is not deterministic (run 1 vs run 2)" || reports[1] != failure {
+		t.Error("Unexpected reports", reports)
+	}
+}
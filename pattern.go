@@ -0,0 +1,288 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Pattern implements comparing partially deterministic text against
+// a golden file of patterns in the style of LLVM's FileCheck.
+//
+// Each line of the golden file is one of:
+//
+//    literal text with {{regexp}} placeholders
+//    CHECK: pattern
+//    CHECK-NEXT: pattern
+//    CHECK-NOT: pattern
+//
+// A plain line must match the next line of the output in full.
+// CHECK finds the first following output line containing the
+// pattern, skipping the lines before it.  CHECK-NEXT must match
+// within the output line right after the previous match.  CHECK-NOT
+// fails if the pattern occurs in the lines between the previous and
+// the next match (or the end of the output).
+//
+// Within patterns, {{re}} matches the regular expression re,
+// [[NAME:re]] matches re and captures the text as NAME and [[NAME]]
+// matches the text captured earlier.  All other text is literal.
+//
+// If the golden file ends with a plain line or CHECK-NEXT, the output
+// must not have any further lines.
+//
+// If the tests are run with -golden flag, the golden file is
+// rewritten to match the output.  Existing golden lines which still
+// match are preserved while the rest of the output is written as
+// literal lines.
+//
+// Example Usage:
+//
+//    test.Pattern(t.Error, "server.log.pattern", logs)
+//
+func Pattern(errorf Errorf, goldenFile, actual string) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil && !(*goldenFlag && os.IsNotExist(err)) {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+
+	p := &patternMatcher{lines: splitLines(actual), captures: map[string]string{}}
+	patterns := splitLines(string(data))
+
+	if !*goldenFlag {
+		if err := p.match(patterns); err != nil {
+			errorf("unexpected output", err.Error())
+		}
+		return
+	}
+
+	if p.match(patterns) == nil {
+		return
+	}
+	p = &patternMatcher{lines: p.lines, captures: map[string]string{}}
+	output := strings.Join(p.regenerate(patterns), "\n") + "\n"
+	if err := ioutil.WriteFile(goldenFile, []byte(output), 0644); err != nil {
+		errorf("Could not save golden output", goldenFile, err)
+	}
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+var patternDirectives = []string{"CHECK-NEXT:", "CHECK-NOT:", "CHECK:"}
+
+// parseDirective splits a golden line into its directive (empty for
+// plain lines) and pattern.
+func parseDirective(line string) (string, string) {
+	trimmed := strings.TrimSpace(line)
+	for _, d := range patternDirectives {
+		if strings.HasPrefix(trimmed, d) {
+			return d[:len(d)-1], strings.TrimSpace(trimmed[len(d):])
+		}
+	}
+	return "", line
+}
+
+type patternMatcher struct {
+	lines    []string
+	pos      int
+	captures map[string]string
+	not      []string
+}
+
+// match checks all the patterns against the output.
+func (p *patternMatcher) match(patterns []string) error {
+	strict := true
+	for idx, line := range patterns {
+		directive, pattern := parseDirective(line)
+		if err := p.step(directive, pattern); err != nil {
+			return fmt.Errorf("golden line %d (%s): %v", idx+1, strings.TrimSpace(line), err)
+		}
+		if directive != "CHECK-NOT" {
+			strict = directive == "" || directive == "CHECK-NEXT"
+		}
+	}
+
+	if err := p.checkNot(len(p.lines)); err != nil {
+		return err
+	}
+	if strict && p.pos < len(p.lines) {
+		return fmt.Errorf("output line %d (%s): unexpected extra output", p.pos+1, p.lines[p.pos])
+	}
+	return nil
+}
+
+// step matches a single golden line, advancing the output position
+// on success.  The matcher is unchanged on failure.
+func (p *patternMatcher) step(directive, pattern string) error {
+	if directive == "CHECK-NOT" {
+		if _, err := compilePattern(pattern, p.captures, false); err != nil {
+			return err
+		}
+		p.not = append(p.not, pattern)
+		return nil
+	}
+
+	re, err := compilePattern(pattern, p.captures, directive == "")
+	if err != nil {
+		return err
+	}
+
+	end := p.pos + 1
+	if directive == "CHECK" {
+		end = len(p.lines)
+	}
+	for k := p.pos; k < end && k < len(p.lines); k++ {
+		m := re.FindStringSubmatch(p.lines[k])
+		if m == nil {
+			continue
+		}
+		if err := p.checkNot(k); err != nil {
+			return err
+		}
+		for idx, name := range re.SubexpNames() {
+			if name != "" {
+				p.captures[name] = m[idx]
+			}
+		}
+		p.pos, p.not = k+1, nil
+		return nil
+	}
+
+	if p.pos >= len(p.lines) {
+		return errors.New("no more output")
+	}
+	if directive == "CHECK" {
+		return fmt.Errorf("no match in output lines %d-%d", p.pos+1, len(p.lines))
+	}
+	return fmt.Errorf("no match at output line %d (%s)", p.pos+1, p.lines[p.pos])
+}
+
+// checkNot verifies the pending CHECK-NOT patterns against the output
+// lines before end.
+func (p *patternMatcher) checkNot(end int) error {
+	for _, pattern := range p.not {
+		re, err := compilePattern(pattern, p.captures, false)
+		if err != nil {
+			return err
+		}
+		for k := p.pos; k < end; k++ {
+			if re.MatchString(p.lines[k]) {
+				return fmt.Errorf("output line %d (%s): matches CHECK-NOT: %s", k+1, p.lines[k], pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// regenerate returns golden lines matching the output, keeping the
+// existing patterns which still match.
+func (p *patternMatcher) regenerate(patterns []string) []string {
+	var result []string
+	strict := true
+	for _, line := range patterns {
+		directive, pattern := parseDirective(line)
+		if directive == "" || directive == "CHECK-NEXT" {
+			// output lines skipped to find a match are kept literally
+			for k := p.pos; k < len(p.lines); k++ {
+				probe := *p
+				probe.pos, probe.captures = k, copyCaptures(p.captures)
+				if probe.step(directive, pattern) == nil {
+					for ; p.pos < k; p.pos++ {
+						result = append(result, escapePattern(p.lines[p.pos]))
+					}
+					break
+				}
+			}
+		}
+
+		if p.step(directive, pattern) == nil {
+			result = append(result, line)
+			if directive != "CHECK-NOT" {
+				strict = directive == "" || directive == "CHECK-NEXT"
+			}
+		}
+	}
+
+	if p.checkNot(len(p.lines)) != nil {
+		for len(result) > 0 {
+			if d, _ := parseDirective(result[len(result)-1]); d != "CHECK-NOT" {
+				break
+			}
+			result = result[:len(result)-1]
+		}
+		strict = true
+	}
+	if strict || len(patterns) == 0 {
+		for ; p.pos < len(p.lines); p.pos++ {
+			result = append(result, escapePattern(p.lines[p.pos]))
+		}
+	}
+	return result
+}
+
+func copyCaptures(captures map[string]string) map[string]string {
+	result := make(map[string]string, len(captures))
+	for k, v := range captures {
+		result[k] = v
+	}
+	return result
+}
+
+// escapePattern returns a plain golden line which matches line
+// literally.
+func escapePattern(line string) string {
+	if d, _ := parseDirective(line); d != "" {
+		line = "{{" + regexp.QuoteMeta(strings.TrimSpace(line)[:1]) + "}}" + strings.TrimSpace(line)[1:]
+	}
+	line = strings.Replace(line, "{{", `{{\{\{}}`, -1)
+	return strings.Replace(line, "[[", `{{\[\[}}`, -1)
+}
+
+var patternRE = regexp.MustCompile(`\{\{(.*?)\}\}|\[\[([A-Za-z_][A-Za-z0-9_]*)(?::(.*?))?\]\]`)
+
+// compilePattern converts a golden pattern into a regular expression.
+func compilePattern(pattern string, captures map[string]string, full bool) (*regexp.Regexp, error) {
+	var buf strings.Builder
+	last := 0
+	for _, m := range patternRE.FindAllStringSubmatchIndex(pattern, -1) {
+		buf.WriteString(regexp.QuoteMeta(pattern[last:m[0]]))
+		last = m[1]
+
+		switch {
+		case m[2] >= 0:
+			buf.WriteString("(?:" + pattern[m[2]:m[3]] + ")")
+		case m[6] >= 0:
+			buf.WriteString("(?P<" + pattern[m[4]:m[5]] + ">" + pattern[m[6]:m[7]] + ")")
+		default:
+			name := pattern[m[4]:m[5]]
+			v, ok := captures[name]
+			if !ok {
+				return nil, fmt.Errorf("undefined capture %s", name)
+			}
+			buf.WriteString(regexp.QuoteMeta(v))
+		}
+	}
+	buf.WriteString(regexp.QuoteMeta(pattern[last:]))
+
+	expr := buf.String()
+	if full {
+		expr = "^" + expr + "$"
+	}
+	return regexp.Compile(expr)
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+const serverLog = `loading config
+starting server pid=4242
+listening on 127.0.0.1:53211
+worker 4242 ready
+request id=9f3ab status=200
+done in 12.5ms
+`
+
+func TestPattern(t *testing.T) {
+	test.Pattern(t.Error, "pattern_server.log", serverLog)
+
+	other := strings.Replace(serverLog, "4242", "17", -1)
+	other = strings.Replace(other, "53211", "8080", 1)
+	test.Pattern(t.Error, "pattern_server.log", "warming up\n"+other)
+}
+
+func TestPatternMismatch(t *testing.T) {
+	cases := map[string]string{
+		"pid mismatch": strings.Replace(serverLog, "worker 4242", "worker 4243", 1),
+		"panic":        strings.Replace(serverLog, "listening", "panic: boo\nlistening", 1),
+		"not next":     strings.Replace(serverLog, "worker", "\nworker", 1),
+		"volatile":     strings.Replace(serverLog, "9f3ab", "xyz", 1),
+		"extra":        serverLog + "bye\n",
+		"truncated":    strings.Replace(serverLog, "done in 12.5ms\n", "", 1),
+		"no server":    "loading config\n",
+	}
+	messages := map[string]string{
+		"pid mismatch": "golden line 4 (CHECK-NEXT: worker [[PID]] ready): no match at output line 4 (worker 4243 ready)",
+		"panic":        "golden line 3 (CHECK: listening on 127.0.0.1:{{[0-9]+}}): output line 3 (panic: boo): matches CHECK-NOT: panic",
+		"not next":     "golden line 4 (CHECK-NEXT: worker [[PID]] ready): no match at output line 4 ()",
+		"volatile":     "golden line 5 (request id={{[0-9a-f]+}} status=200): no match at output line 5 (request id=xyz status=200)",
+		"extra":        "output line 7 (bye): unexpected extra output",
+		"truncated":    "golden line 6 (done in {{[0-9.]+}}ms): no more output",
+		"no server":    "golden line 1 (CHECK: starting server pid=[[PID:[0-9]+]]): no match in output lines 1-1",
+	}
+
+	for name, output := range cases {
+		var failed []interface{}
+		errorf := func(args ...interface{}) {
+			failed = args
+		}
+		test.Pattern(errorf, "pattern_server.log", output)
+		if len(failed) != 2 || failed[0] != "unexpected output" || failed[1] != messages[name] {
+			t.Errorf("%s: unexpected failure %#v", name, failed)
+		}
+	}
+}
+
+func TestPatternGolden(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	data, err := ioutil.ReadFile("testdata/pattern_server.log")
+	check(err)
+	check(ioutil.WriteFile("testdata/pattern_regen.log", data, 0644))
+	defer os.Remove(filepath.Join("testdata", "pattern_regen.log"))
+
+	setGoldenFlag(true)
+	output := strings.Replace(serverLog, "status=200", "status=500", 1) + "bye {{x}}\n"
+	test.Pattern(t.Error, "pattern_regen.log", output)
+
+	data, err = ioutil.ReadFile("testdata/pattern_regen.log")
+	check(err)
+	expected := `CHECK: starting server pid=[[PID:[0-9]+]]
+CHECK-NOT: panic
+CHECK: listening on 127.0.0.1:{{[0-9]+}}
+CHECK-NEXT: worker [[PID]] ready
+request id=9f3ab status=500
+done in {{[0-9.]+}}ms
+bye {{\{\{}}x}}
+`
+	if string(data) != expected {
+		t.Errorf("Unexpected golden file %q", data)
+	}
+
+	setGoldenFlag(false)
+	test.Pattern(t.Error, "pattern_regen.log", output)
+	test.Pattern(t.Error, "pattern_regen.log", strings.Replace(output, "4242", "1", -1))
+}
+
+func TestPatternErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.Pattern(errorf, "non-existent.log", serverLog)
+	test.Pattern(errorf, "pattern_server.log", "")
+	if len(failed) != 2 || failed[0] != "error reading" || failed[1] != "unexpected output" {
+		t.Errorf("Unexpected failures %#v", failed)
+	}
+}
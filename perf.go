@@ -0,0 +1,262 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Perf implements comparing benchmark metrics against a recorded
+// baseline.
+//
+// The benchmark is run Runs times via testing.Benchmark and the
+// samples of each metric (ns/op, B/op, allocs/op, MB/s when
+// b.SetBytes is used and any custom b.ReportMetric units) are
+// compared against the baseline samples stored in the golden file.
+//
+// A metric regresses if its mean is worse than the baseline mean by
+// more than the tolerance and a one-sided Welch t-test finds the
+// difference significant at level Alpha.  Metrics whose unit ends
+// with "/s" are considered better when higher, all others when
+// lower.
+//
+// If the tests are run with -golden flag, the metrics are not
+// compared but instead the baseline is recorded.
+//
+// Example Usage:
+//
+//    test.Perf{Runs: 10}.Check(t.Error, "parse_perf.json", BenchmarkParse)
+//
+type Perf struct {
+	// Runs is the number of times the benchmark is run. It
+	// defaults to 5.
+	Runs int
+
+	// Tolerance is the allowed relative regression for each
+	// metric unit.  The "*" entry applies to units that are not
+	// listed and defaults to 0.1 (i.e. 10%).
+	Tolerance map[string]float64
+
+	// Alpha is the significance level of the statistical check.
+	// It defaults to 0.05.
+	Alpha float64
+}
+
+// Check runs the benchmark and compares its metrics against the
+// golden file, which is relative to the testdata/ folder of the
+// caller.
+func (p Perf) Check(errorf Errorf, goldenFile string, fn func(b *testing.B)) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+
+	runs := p.Runs
+	if runs <= 0 {
+		runs = 5
+	}
+
+	actual := map[string][]float64{}
+	for kk := 0; kk < runs; kk++ {
+		for unit, v := range metrics(testing.Benchmark(fn)) {
+			actual[unit] = append(actual[unit], v)
+		}
+	}
+
+	if *goldenFlag {
+		bytes, err := json.MarshalIndent(actual, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(goldenFile, bytes, 0644)
+		}
+		if err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	var expected map[string][]float64
+	bytes, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	if err := json.Unmarshal(bytes, &expected); err != nil {
+		errorf("could not unmarshal golden file", err)
+		return
+	}
+
+	units := make([]string, 0, len(expected))
+	for unit := range expected {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+
+	var regressions []string
+	for _, unit := range units {
+		if _, ok := actual[unit]; !ok {
+			regressions = append(regressions, unit+": metric not reported")
+			continue
+		}
+		if r := p.regression(unit, expected[unit], actual[unit]); r != "" {
+			regressions = append(regressions, r)
+		}
+	}
+
+	if len(regressions) > 0 {
+		errorf("performance regression", strings.Join(regressions, "\n"))
+	}
+}
+
+// regression returns a description of the regression of the metric
+// or an empty string if there is none.
+func (p Perf) regression(unit string, baseline, samples []float64) string {
+	tolerance, ok := p.Tolerance[unit]
+	if !ok {
+		if tolerance, ok = p.Tolerance["*"]; !ok {
+			tolerance = 0.1
+		}
+	}
+	alpha := p.Alpha
+	if alpha <= 0 {
+		alpha = 0.05
+	}
+
+	m1, v1 := meanVariance(baseline)
+	m2, v2 := meanVariance(samples)
+
+	// worse is the relative change oriented so that positive is
+	// a regression.
+	worse := (m2 - m1) / math.Abs(m1)
+	if strings.HasSuffix(unit, "/s") {
+		worse = -worse
+	}
+	if m1 == 0 {
+		worse = math.Copysign(math.Inf(1), worse)
+		if m2 == 0 {
+			worse = 0
+		}
+	}
+
+	if worse <= tolerance {
+		return ""
+	}
+
+	pvalue := welch(baseline, samples) / 2
+	if pvalue >= alpha {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"%s: %.4g (±%.2g) -> %.4g (±%.2g), %+.1f%% (p=%.3g)",
+		unit, m1, math.Sqrt(v1), m2, math.Sqrt(v2), worse*100, pvalue,
+	)
+}
+
+func metrics(r testing.BenchmarkResult) map[string]float64 {
+	result := map[string]float64{
+		"ns/op":     float64(r.NsPerOp()),
+		"B/op":      float64(r.AllocedBytesPerOp()),
+		"allocs/op": float64(r.AllocsPerOp()),
+	}
+	if r.Bytes > 0 && r.T > 0 {
+		result["MB/s"] = float64(r.Bytes) * float64(r.N) / 1e6 / r.T.Seconds()
+	}
+	for unit, v := range r.Extra {
+		result[unit] = v
+	}
+	return result
+}
+
+func meanVariance(samples []float64) (mean, variance float64) {
+	for _, s := range samples {
+		mean += s
+	}
+	mean /= float64(len(samples))
+	if len(samples) < 2 {
+		return mean, 0
+	}
+	for _, s := range samples {
+		variance += (s - mean) * (s - mean)
+	}
+	return mean, variance / float64(len(samples)-1)
+}
+
+// welch returns the two-sided p-value of Welch's t-test for the
+// difference of means of the two samples.
+func welch(a, b []float64) float64 {
+	m1, v1 := meanVariance(a)
+	m2, v2 := meanVariance(b)
+	n1, n2 := float64(len(a)), float64(len(b))
+
+	se := v1/n1 + v2/n2
+	if se == 0 {
+		if m1 == m2 {
+			return 1
+		}
+		return 0
+	}
+	if n1 < 2 || n2 < 2 {
+		// not enough samples to estimate the variance of
+		// both sides, so fall back to the tolerance alone.
+		return 0
+	}
+
+	t := (m2 - m1) / math.Sqrt(se)
+	df := se * se / ((v1/n1)*(v1/n1)/(n1-1) + (v2/n2)*(v2/n2)/(n2-1))
+	return incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// incompleteBeta computes the regularized incomplete beta function
+// I_x(a, b) using its continued fraction expansion.
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	if x > (a+1)/(a+b+2) {
+		return 1 - incompleteBeta(b, a, 1-x)
+	}
+
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab-la-lb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	const tiny = 1e-30
+	f, c, d := 1.0, 1.0, 0.0
+	for i := 0; i <= 200; i++ {
+		m := float64(i / 2)
+		var numerator float64
+		switch {
+		case i == 0:
+			numerator = 1
+		case i%2 == 0:
+			numerator = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		f *= c * d
+		if math.Abs(1-c*d) < 1e-12 {
+			break
+		}
+	}
+	return front * (f - 1)
+}
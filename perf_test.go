@@ -0,0 +1,104 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"flag"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func widgets(count float64) func(b *testing.B) {
+	return func(b *testing.B) {
+		for kk := 0; kk < b.N; kk++ {
+			_ = strings.Repeat("x", 10)
+		}
+		b.ReportMetric(count, "widgets/op")
+	}
+}
+
+func TestPerf(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer restoreFlag("test.benchtime")()
+	check(flag.Set("test.benchtime", "100x"))
+
+	_, fname, _, _ := runtime.Caller(0)
+	golden := path.Join(path.Dir(fname), "testdata/golden_perf.json")
+	defer os.Remove(golden)
+
+	perf := test.Perf{Runs: 3, Tolerance: map[string]float64{"*": 1000, "widgets/op": 0.1}}
+
+	check(flag.Set("golden", "true"))
+	perf.Check(t.Error, "golden_perf.json", widgets(10))
+	check(flag.Set("golden", "false"))
+	perf.Check(t.Error, "golden_perf.json", widgets(10))
+	perf.Check(t.Error, "golden_perf.json", widgets(10.5))
+
+	var report string
+	errorf := func(args ...interface{}) {
+		report = args[1].(string)
+	}
+	perf.Check(errorf, "golden_perf.json", widgets(20))
+	if !strings.Contains(report, "widgets/op: 10 (±0) -> 20 (±0), +100.0%") {
+		t.Error("Unexpected report", report)
+	}
+
+	report = ""
+	perf.Check(errorf, "golden_perf.json", widgets(5))
+	if report != "" {
+		t.Error("Unexpected regression", report)
+	}
+}
+
+func TestPerfMissing(t *testing.T) {
+	defer restoreFlag("test.benchtime")()
+	check(flag.Set("test.benchtime", "1x"))
+
+	failed := false
+	errorf := func(args ...interface{}) {
+		failed = true
+	}
+
+	test.Perf{Runs: 1}.Check(errorf, "non-existent", widgets(1))
+	if !failed {
+		t.Error("Failed to fail")
+	}
+}
+
+func TestPerfStatistics(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer restoreFlag("test.benchtime")()
+	check(flag.Set("test.benchtime", "1x"))
+
+	_, fname, _, _ := runtime.Caller(0)
+	golden := path.Join(path.Dir(fname), "testdata/golden_perf_noisy.json")
+	defer os.Remove(golden)
+
+	samples := []float64{10, 14, 9, 15, 12, 8}
+	noisy := func(b *testing.B) {
+		b.ReportMetric(samples[0], "widgets/op")
+		samples = samples[1:]
+	}
+
+	perf := test.Perf{Runs: 3, Tolerance: map[string]float64{"*": 1000, "widgets/op": 0.01}}
+	check(flag.Set("golden", "true"))
+	perf.Check(t.Error, "golden_perf_noisy.json", noisy)
+
+	// a 6% increase in the mean is not significant given the noise
+	check(flag.Set("golden", "false"))
+	perf.Check(t.Error, "golden_perf_noisy.json", noisy)
+}
+
+func restoreFlag(name string) func() {
+	before := flag.Lookup(name).Value.String()
+	return func() {
+		check(flag.Set(name, before))
+	}
+}
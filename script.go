@@ -0,0 +1,409 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+// Script implements script driven integration tests.
+//
+// Each script is a txtar archive: the comment section holds the
+// script and the files are extracted into a fresh temporary directory
+// ($WORK) which is the initial working directory.  Each line of the
+// script is a command followed by its arguments, which are separated
+// by spaces and can be quoted with single quotes.  Environment
+// variables ($NAME or ${NAME}) are expanded outside of quotes.  Lines
+// starting with # are comments.
+//
+// The following commands are supported:
+//
+//    exec program [args...]   run a program, failing on non-zero exit
+//    stdout regexp            match the stdout of the last exec
+//    stderr regexp            match the stderr of the last exec
+//    cmp file1 file2          compare two files (file1 can be stdout or stderr)
+//    env KEY=VALUE...         set environment variables
+//    cd dir                   change the working directory
+//
+// When comparing stdout or stderr with cmp, the path of the work
+// directory is replaced with $WORK.
+//
+// Any command can be prefixed with ! to indicate that it is expected
+// to fail.  For exec, only a non-zero exit status is such a failure.
+// Scripts stop at the first failing command.
+//
+// If the tests are run with -golden flag, cmp commands whose second
+// file is part of the archive rewrite that file with the actual
+// contents and the script is updated in place.
+//
+// Example Usage:
+//
+//    test.Script{
+//        Programs: map[string]string{"testmd": "./cmd/testmd"},
+//    }.Run(t, "scripts/*.txtar")
+//
+type Script struct {
+	// Programs maps program names used with exec to main
+	// packages (as with Exec) which are built before use.
+	Programs map[string]string
+
+	// Commands holds additional commands available to scripts.
+	Commands map[string]ScriptCommand
+}
+
+// ScriptCommand is the type of user provided script commands.  The
+// command fails by returning an error.
+type ScriptCommand func(s *ScriptState, args []string) error
+
+// ScriptState holds the state of a running script.
+type ScriptState struct {
+	// Work is the temporary directory the archive is extracted into.
+	Work string
+
+	// Dir is the current working directory.
+	Dir string
+
+	// Stdout and Stderr hold the output of the last exec.
+	Stdout, Stderr string
+
+	env     map[string]string
+	archive *txtar.Archive
+	dirty   bool
+	script  Script
+	caller  string
+	binDir  string
+	bins    map[string]string
+}
+
+// Path returns the path of name relative to the current directory.
+func (s *ScriptState) Path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.Dir, name)
+}
+
+// Getenv returns the value of the script environment variable.
+func (s *ScriptState) Getenv(key string) string {
+	if v, ok := s.env[key]; ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// Setenv sets the script environment variable.
+func (s *ScriptState) Setenv(key, value string) {
+	s.env[key] = value
+}
+
+// Run runs every script matching the glob (relative to the testdata/
+// folder of the caller) as a subtest.
+func (s Script) Run(t *testing.T, glob string) {
+	testdata := callerTestdata(2)
+	matches, err := filepath.Glob(filepath.Join(testdata, glob))
+	if err != nil {
+		t.Fatal("invalid glob", glob, err)
+	}
+
+	for _, match := range matches {
+		match := match
+		name := strings.TrimSuffix(filepath.Base(match), filepath.Ext(match))
+		t.Run(name, func(t *testing.T) {
+			s.run(t, testdata, match)
+		})
+	}
+}
+
+func (s Script) run(t *testing.T, testdata, path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("error reading", path, err)
+	}
+
+	work, err := ioutil.TempDir("", "test-script")
+	if err != nil {
+		t.Fatal("could not create work directory", err)
+	}
+	defer os.RemoveAll(work)
+
+	state := &ScriptState{
+		Work:    work,
+		Dir:     work,
+		env:     map[string]string{"WORK": work, "TESTDATA": testdata},
+		archive: txtar.Parse(data),
+		script:  s,
+		caller:  filepath.Dir(testdata),
+		binDir:  t.TempDir(),
+		bins:    map[string]string{},
+	}
+
+	for _, f := range state.archive.Files {
+		if !fs.ValidPath(f.Name) {
+			t.Fatal("invalid file name", f.Name)
+		}
+		name := filepath.Join(work, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal("could not extract", f.Name, err)
+		}
+		if err := ioutil.WriteFile(name, f.Data, 0644); err != nil {
+			t.Fatal("could not extract", f.Name, err)
+		}
+	}
+
+	lines := strings.Split(string(state.archive.Comment), "\n")
+	for idx, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		t.Logf("> %s", line)
+		if err := state.exec(line); err != nil {
+			t.Errorf("%s:%d: %s: %v", filepath.Base(path), idx+1, line, err)
+			break
+		}
+	}
+
+	if state.dirty {
+		if err := ioutil.WriteFile(path, txtar.Format(state.archive), 0644); err != nil {
+			t.Error("Could not save golden output", path, err)
+		}
+	}
+}
+
+// exec runs a single line of the script.
+func (s *ScriptState) exec(line string) error {
+	neg := false
+	if strings.HasPrefix(line, "!") {
+		neg = true
+		line = strings.TrimSpace(line[1:])
+	}
+
+	args, err := s.split(line)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("missing command")
+	}
+
+	var cmd ScriptCommand
+	if c, ok := s.script.Commands[args[0]]; ok {
+		cmd = c
+	} else if c, ok := scriptCommands[args[0]]; ok {
+		cmd = c
+	} else {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	// programs are expected to fail by exiting with a non-zero
+	// status rather than for example not being found
+	err = cmd(s, args[1:])
+	var exit *exec.ExitError
+	switch {
+	case neg && err == nil:
+		return errors.New("unexpected success")
+	case neg && args[0] == "exec" && !errors.As(err, &exit):
+		return err
+	case neg:
+		return nil
+	}
+	return err
+}
+
+// split breaks the line into words, honoring single quotes and
+// expanding environment variables outside of them.
+func (s *ScriptState) split(line string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord, quoted := false, false
+
+	for idx := 0; idx < len(line); idx++ {
+		c := line[idx]
+		switch {
+		case quoted && c == '\'':
+			if idx+1 < len(line) && line[idx+1] == '\'' {
+				word.WriteByte('\'')
+				idx++
+			} else {
+				quoted = false
+			}
+		case quoted:
+			word.WriteByte(c)
+		case c == '\'':
+			quoted, inWord = true, true
+		case c == ' ' || c == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			end := idx + 1
+			for end < len(line) && !strings.ContainsRune(" \t'", rune(line[end])) {
+				end++
+			}
+			word.WriteString(os.Expand(line[idx:end], s.Getenv))
+			inWord = true
+			idx = end - 1
+		}
+	}
+
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// environ returns the environment for programs run by the script.
+func (s *ScriptState) environ() []string {
+	env := os.Environ()
+	for _, key := range sortedKeys(s.env) {
+		env = append(env, key+"="+s.env[key])
+	}
+	return env
+}
+
+var scriptCommands = map[string]ScriptCommand{
+	"exec": func(s *ScriptState, args []string) error {
+		if len(args) == 0 {
+			return errors.New("usage: exec program [args...]")
+		}
+
+		program := args[0]
+		if pkg, ok := s.script.Programs[program]; ok {
+			bin, ok := s.bins[program]
+			if !ok {
+				var err error
+				if bin, err = build(s.caller, pkg, filepath.Join(s.binDir, program)); err != nil {
+					return err
+				}
+				s.bins[program] = bin
+			}
+			program = bin
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(program, args[1:]...)
+		cmd.Dir = s.Dir
+		cmd.Env = s.environ()
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		s.Stdout, s.Stderr = stdout.String(), stderr.String()
+		return err
+	},
+	"stdout": func(s *ScriptState, args []string) error {
+		return match("stdout", s.Stdout, args)
+	},
+	"stderr": func(s *ScriptState, args []string) error {
+		return match("stderr", s.Stderr, args)
+	},
+	"cmp": func(s *ScriptState, args []string) error {
+		if len(args) != 2 {
+			return errors.New("usage: cmp file1 file2")
+		}
+
+		actual, err := s.read(args[0])
+		if err != nil {
+			return err
+		}
+		expected, err := s.read(args[1])
+		if err != nil {
+			return err
+		}
+		if actual == expected {
+			return nil
+		}
+
+		if *goldenFlag {
+			if f := s.archiveFile(args[1]); f != nil {
+				f.Data = []byte(actual)
+				s.dirty = true
+				return ioutil.WriteFile(s.Path(args[1]), f.Data, 0644)
+			}
+		}
+		return errors.New(linediff(expected, actual))
+	},
+	"env": func(s *ScriptState, args []string) error {
+		for _, arg := range args {
+			kv := strings.SplitN(arg, "=", 2)
+			if len(kv) != 2 {
+				return errors.New("usage: env KEY=VALUE...")
+			}
+			s.Setenv(kv[0], kv[1])
+		}
+		return nil
+	},
+	"cd": func(s *ScriptState, args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: cd dir")
+		}
+		dir := s.Path(args[0])
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", args[0])
+		}
+		s.Dir = dir
+		return nil
+	},
+}
+
+func match(name, output string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + name + " regexp")
+	}
+	re, err := regexp.Compile("(?m)" + args[0])
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(output) {
+		return fmt.Errorf("no match for %q in %s:\n%s", args[0], name, output)
+	}
+	return nil
+}
+
+// read returns the contents of the file, or the output of the last
+// exec for stdout and stderr with the work directory replaced by
+// $WORK.
+func (s *ScriptState) read(name string) (string, error) {
+	vars := map[string]string{"WORK": s.Work}
+	switch name {
+	case "stdout":
+		return placeholders(s.Stdout, vars), nil
+	case "stderr":
+		return placeholders(s.Stderr, vars), nil
+	}
+	data, err := ioutil.ReadFile(s.Path(name))
+	return string(data), err
+}
+
+// archiveFile returns the archive file extracted to name, if any.
+func (s *ScriptState) archiveFile(name string) *txtar.File {
+	rel, err := filepath.Rel(s.Work, s.Path(name))
+	if err != nil {
+		return nil
+	}
+	for kk := range s.archive.Files {
+		if s.archive.Files[kk].Name == filepath.ToSlash(rel) {
+			return &s.archive.Files[kk]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+var script = test.Script{
+	Programs: map[string]string{"cat": "./testdata/cat"},
+	Commands: map[string]test.ScriptCommand{
+		"greet": func(s *test.ScriptState, args []string) error {
+			if len(args) != 1 {
+				return errors.New("usage: greet name")
+			}
+			s.Stdout = "hello " + args[0] + "\n"
+			return nil
+		},
+	},
+}
+
+func TestScript(t *testing.T) {
+	script.Run(t, "script/*.txtar")
+}
+
+func TestScriptGolden(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	_, fname, _, _ := runtime.Caller(0)
+	dir := path.Join(path.Dir(fname), "testdata")
+	data, err := ioutil.ReadFile(path.Join(dir, "script_golden.txtar.in"))
+	check(err)
+	check(ioutil.WriteFile(path.Join(dir, "script_golden.txtar"), data, 0644))
+	defer os.Remove(path.Join(dir, "script_golden.txtar"))
+
+	check(flag.Set("golden", "true"))
+	script.Run(t, "script_golden.txtar")
+	check(flag.Set("golden", "false"))
+	script.Run(t, "script_golden.txtar")
+
+	data, err = ioutil.ReadFile(path.Join(dir, "script_golden.txtar"))
+	check(err)
+	if !strings.Contains(string(data), "-- want.txt --\nargs: []\ndir: $WORK\n") {
+		t.Error("Unexpected script", string(data))
+	}
+}
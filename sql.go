@@ -0,0 +1,568 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SQL implements recording and replaying database/sql interactions.
+//
+// The driver.Connector returned by Connector replays the queries
+// recorded in a golden file in order, failing with a diff if the code
+// issues a different query or arguments.  Closing the sql.DB reports
+// the recorded queries which were not issued.  If the tests are run with
+// -golden flag, the queries are instead executed against Driver and
+// every query, its arguments and the resulting rows or error are
+// recorded.
+//
+// Example Usage:
+//
+//    db := sql.OpenDB(test.SQL{Driver: &pq.Driver{}, DSN: dsn}.
+//        Connector(t.Error, "users.sql.json"))
+//    defer db.Close()
+//
+type SQL struct {
+	// Driver is the real driver used when recording.
+	Driver driver.Driver
+
+	// DSN is the data source name passed to Driver.
+	DSN string
+}
+
+// Connector returns a connector for use with sql.OpenDB.  The golden
+// file is relative to the testdata/ folder of the caller.
+func (s SQL) Connector(errorf Errorf, goldenFile string) driver.Connector {
+	l := &sqlLog{errorf: errorf, file: filepath.Join(callerTestdata(2), goldenFile)}
+	if *goldenFlag {
+		l.save()
+		return &sqlConnector{s, l}
+	}
+
+	data, err := ioutil.ReadFile(l.file)
+	if err != nil {
+		errorf("error reading", l.file, err)
+	} else if err := json.Unmarshal(data, &l.calls); err != nil {
+		errorf("could not unmarshal golden file", l.file, err)
+	}
+	return &sqlConnector{s, l}
+}
+
+// sqlCall is a single recorded interaction.
+type sqlCall struct {
+	Op           string       `json:"op"`
+	Query        string       `json:"query,omitempty"`
+	Args         []sqlValue   `json:"args,omitempty"`
+	Columns      []string     `json:"columns,omitempty"`
+	Rows         [][]sqlValue `json:"rows,omitempty"`
+	RowsAffected int64        `json:"rowsAffected,omitempty"`
+	LastInsertID int64        `json:"lastInsertId,omitempty"`
+	Error        string       `json:"error,omitempty"`
+}
+
+func (c *sqlCall) err() error {
+	if c.Error == "" {
+		return nil
+	}
+	return errors.New(c.Error)
+}
+
+// sqlLog holds the calls shared by all connections of a connector.
+type sqlLog struct {
+	errorf Errorf
+	file   string
+
+	sync.Mutex
+	calls []sqlCall
+	next  int
+}
+
+// replay returns the next recorded call after checking that it
+// matches the actual call.
+func (l *sqlLog) replay(actual sqlCall) (*sqlCall, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.next >= len(l.calls) {
+		l.errorf("unexpected query", actual.Op, actual.Query)
+		return nil, errors.New("test: no more recorded queries")
+	}
+
+	// the calls are compared via their JSON encoding which also
+	// records the type of each argument
+	expected := l.calls[l.next]
+	want, err := json.MarshalIndent(sqlCall{Op: expected.Op, Query: expected.Query, Args: expected.Args}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	got, err := json.MarshalIndent(actual, "", "  ")
+	if err != nil {
+		l.errorf("Could not marshal value", err)
+		return nil, err
+	}
+	if !bytes.Equal(want, got) {
+		l.errorf("unexpected query", linediff(string(want), string(got)))
+		return nil, errors.New("test: query does not match recording")
+	}
+	l.next++
+	return &expected, nil
+}
+
+// unused reports the recorded calls which were not replayed.
+func (l *sqlLog) unused() {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, c := range l.calls[l.next:] {
+		l.errorf("unused query", c.Op, c.Query)
+	}
+}
+
+// record appends the call and saves the golden file.
+func (l *sqlLog) record(c sqlCall) {
+	l.Lock()
+	defer l.Unlock()
+	l.calls = append(l.calls, c)
+	l.save()
+}
+
+func (l *sqlLog) save() {
+	calls := l.calls
+	if calls == nil {
+		calls = []sqlCall{}
+	}
+	data, err := json.MarshalIndent(calls, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(l.file, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		l.errorf("Could not save golden output", l.file, err)
+	}
+}
+
+type sqlConnector struct {
+	s   SQL
+	log *sqlLog
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if !*goldenFlag {
+		return &sqlConn{log: c.log}, nil
+	}
+
+	if c.s.Driver == nil {
+		return nil, errors.New("test: no driver to record with")
+	}
+	conn, err := c.s.Driver.Open(c.s.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{log: c.log, real: conn}, nil
+}
+
+// Close reports the recorded queries which were not replayed.  It is
+// called by the Close method of sql.DB.
+func (c *sqlConnector) Close() error {
+	if !*goldenFlag {
+		c.log.unused()
+	}
+	return nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return sqlDriver{c}
+}
+
+type sqlDriver struct {
+	c *sqlConnector
+}
+
+func (d sqlDriver) Open(name string) (driver.Conn, error) {
+	return d.c.Connect(context.Background())
+}
+
+// sqlConn records calls made on real or replays them if real is nil.
+type sqlConn struct {
+	log  *sqlLog
+	real driver.Conn
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return &sqlStmt{c, query}, nil
+}
+
+func (c *sqlConn) Close() error {
+	if c.real != nil {
+		return c.real.Close()
+	}
+	return nil
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	call := sqlCall{Op: "begin"}
+	if c.real == nil {
+		recorded, err := c.log.replay(call)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlTx{c, nil}, recorded.err()
+	}
+
+	var tx driver.Tx
+	var err error
+	if b, ok := c.real.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.real.Begin()
+	}
+	call.Error = errorString(err)
+	c.log.record(call)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{c, tx}, nil
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.real.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	call := sqlCall{Op: "query", Query: query, Args: sqlValues(args)}
+	if c.real == nil {
+		return c.replayQuery(call)
+	}
+
+	rows, err := c.realQuery(ctx, query, args)
+	if err == nil {
+		call.Columns = rows.Columns()
+		call.Rows, err = readRows(rows)
+	}
+	call.Error = errorString(err)
+	c.log.record(call)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRows{columns: call.Columns, rows: call.Rows}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	call := sqlCall{Op: "exec", Query: query, Args: sqlValues(args)}
+	if c.real == nil {
+		recorded, err := c.log.replay(call)
+		if err != nil {
+			return nil, err
+		}
+		if err := recorded.err(); err != nil {
+			return nil, err
+		}
+		return sqlResult{recorded.LastInsertID, recorded.RowsAffected}, nil
+	}
+
+	result, err := c.realExec(ctx, query, args)
+	if err == nil {
+		call.LastInsertID, _ = result.LastInsertId()
+		call.RowsAffected, _ = result.RowsAffected()
+	}
+	call.Error = errorString(err)
+	c.log.record(call)
+	if err != nil {
+		return nil, err
+	}
+	return sqlResult{call.LastInsertID, call.RowsAffected}, nil
+}
+
+func (c *sqlConn) replayQuery(call sqlCall) (driver.Rows, error) {
+	recorded, err := c.log.replay(call)
+	if err != nil {
+		return nil, err
+	}
+	if err := recorded.err(); err != nil {
+		return nil, err
+	}
+	return &sqlRows{columns: recorded.Columns, rows: recorded.Rows}, nil
+}
+
+func (c *sqlConn) realQuery(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.real.(driver.QueryerContext); ok {
+		rows, err := q.QueryContext(ctx, query, args)
+		if err != driver.ErrSkip {
+			return rows, err
+		}
+	}
+
+	stmt, err := c.real.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	if s, ok := stmt.(driver.StmtQueryContext); ok {
+		return s.QueryContext(ctx, args)
+	}
+	return stmt.Query(plainValues(args))
+}
+
+func (c *sqlConn) realExec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.real.(driver.ExecerContext); ok {
+		result, err := e.ExecContext(ctx, query, args)
+		if err != driver.ErrSkip {
+			return result, err
+		}
+	}
+
+	stmt, err := c.real.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	if s, ok := stmt.(driver.StmtExecContext); ok {
+		return s.ExecContext(ctx, args)
+	}
+	return stmt.Exec(plainValues(args))
+}
+
+// sqlStmt defers all work to the connection when executed.
+type sqlStmt struct {
+	c     *sqlConn
+	query string
+}
+
+func (s *sqlStmt) Close() error {
+	return nil
+}
+
+func (s *sqlStmt) NumInput() int {
+	return -1
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+type sqlTx struct {
+	c    *sqlConn
+	real driver.Tx
+}
+
+func (tx *sqlTx) Commit() error {
+	return tx.finish("commit", func() error { return tx.real.Commit() })
+}
+
+func (tx *sqlTx) Rollback() error {
+	return tx.finish("rollback", func() error { return tx.real.Rollback() })
+}
+
+func (tx *sqlTx) finish(op string, fn func() error) error {
+	call := sqlCall{Op: op}
+	if tx.real == nil {
+		recorded, err := tx.c.log.replay(call)
+		if err != nil {
+			return err
+		}
+		return recorded.err()
+	}
+
+	err := fn()
+	call.Error = errorString(err)
+	tx.c.log.record(call)
+	return err
+}
+
+type sqlResult struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r sqlResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r sqlResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// sqlRows serves rows from memory.
+type sqlRows struct {
+	columns []string
+	rows    [][]sqlValue
+}
+
+func (r *sqlRows) Columns() []string {
+	return r.columns
+}
+
+func (r *sqlRows) Close() error {
+	return nil
+}
+
+func (r *sqlRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for idx, v := range r.rows[0] {
+		dest[idx] = v.v
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func readRows(rows driver.Rows) ([][]sqlValue, error) {
+	defer rows.Close()
+
+	var result [][]sqlValue
+	dest := make([]driver.Value, len(rows.Columns()))
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			return result, nil
+		} else if err != nil {
+			return result, err
+		}
+		row := make([]sqlValue, len(dest))
+		for idx, v := range dest {
+			row[idx] = sqlValue{copyValue(v)}
+		}
+		result = append(result, row)
+	}
+}
+
+// copyValue copies byte slices which drivers may reuse.
+func copyValue(v driver.Value) driver.Value {
+	if b, ok := v.([]byte); ok {
+		return append([]byte{}, b...)
+	}
+	return v
+}
+
+func sqlValues(args []driver.NamedValue) []sqlValue {
+	if len(args) == 0 {
+		return nil
+	}
+	result := make([]sqlValue, len(args))
+	for idx, arg := range args {
+		result[idx] = sqlValue{arg.Value}
+	}
+	return result
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, len(args))
+	for idx, arg := range args {
+		result[idx] = driver.NamedValue{Ordinal: idx + 1, Value: arg}
+	}
+	return result
+}
+
+func plainValues(args []driver.NamedValue) []driver.Value {
+	result := make([]driver.Value, len(args))
+	for idx, arg := range args {
+		result[idx] = arg.Value
+	}
+	return result
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// sqlValue is a driver.Value which is stored in JSON along with its
+// type, such as {"int64": 5}.
+type sqlValue struct {
+	v driver.Value
+}
+
+func (v sqlValue) MarshalJSON() ([]byte, error) {
+	switch x := v.v.(type) {
+	case nil:
+		return []byte("null"), nil
+	case int64:
+		return json.Marshal(map[string]int64{"int64": x})
+	case float64:
+		return json.Marshal(map[string]float64{"float64": x})
+	case bool:
+		return json.Marshal(map[string]bool{"bool": x})
+	case []byte:
+		return json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString(x)})
+	case string:
+		return json.Marshal(map[string]string{"string": x})
+	case time.Time:
+		return json.Marshal(map[string]string{"time": x.Format(time.RFC3339Nano)})
+	}
+	return nil, fmt.Errorf("test: unsupported driver value %T", v.v)
+}
+
+func (v *sqlValue) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil || m == nil {
+		v.v = nil
+		return err
+	}
+
+	var err error
+	for kind, raw := range m {
+		switch kind {
+		case "int64":
+			var x int64
+			err = json.Unmarshal(raw, &x)
+			v.v = x
+		case "float64":
+			var x float64
+			err = json.Unmarshal(raw, &x)
+			v.v = x
+		case "bool":
+			var x bool
+			err = json.Unmarshal(raw, &x)
+			v.v = x
+		case "bytes":
+			var x string
+			if err = json.Unmarshal(raw, &x); err == nil {
+				v.v, err = base64.StdEncoding.DecodeString(x)
+			}
+		case "string":
+			var x string
+			err = json.Unmarshal(raw, &x)
+			v.v = x
+		case "time":
+			var x string
+			if err = json.Unmarshal(raw, &x); err == nil {
+				v.v, err = time.Parse(time.RFC3339Nano, x)
+			}
+		default:
+			err = fmt.Errorf("test: unsupported driver value type %s", kind)
+		}
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tvastar/test"
+)
+
+// echoDriver returns one row per argument for queries and fails
+// queries that start with "fail".
+type echoDriver struct{}
+
+func (echoDriver) Open(name string) (driver.Conn, error) {
+	return echoConn{}, nil
+}
+
+type echoConn struct{}
+
+func (echoConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.HasPrefix(query, "fail") {
+		return nil, errors.New("failed: " + query)
+	}
+	return echoStmt(query), nil
+}
+
+func (echoConn) Close() error {
+	return nil
+}
+
+func (echoConn) Begin() (driver.Tx, error) {
+	return echoTx{}, nil
+}
+
+type echoTx struct{}
+
+func (echoTx) Commit() error {
+	return nil
+}
+
+func (echoTx) Rollback() error {
+	return errors.New("rollback failed")
+}
+
+type echoStmt string
+
+func (echoStmt) Close() error {
+	return nil
+}
+
+func (echoStmt) NumInput() int {
+	return -1
+}
+
+func (s echoStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s echoStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &echoRows{string(s), args}, nil
+}
+
+type echoRows struct {
+	query string
+	args  []driver.Value
+}
+
+func (r *echoRows) Columns() []string {
+	return []string{"query", "arg"}
+}
+
+func (r *echoRows) Close() error {
+	return nil
+}
+
+func (r *echoRows) Next(dest []driver.Value) error {
+	if len(r.args) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.query, r.args[0]
+	r.args = r.args[1:]
+	return nil
+}
+
+func useDatabase(db *sql.DB) string {
+	var result []string
+	when := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	rows, err := db.Query("select", 1, "two", []byte{3}, 4.5, true, nil, when)
+	if err != nil {
+		return err.Error()
+	}
+	for rows.Next() {
+		var query string
+		var arg interface{}
+		check(rows.Scan(&query, &arg))
+		result = append(result, fmt.Sprintf("%s %T %v", query, arg, arg))
+	}
+	check(rows.Close())
+
+	res, err := db.Exec("update", 1, 2)
+	if err != nil {
+		return err.Error()
+	}
+	n, _ := res.RowsAffected()
+	result = append(result, fmt.Sprint("affected ", n))
+
+	_, err = db.Query("fail now")
+	result = append(result, fmt.Sprint(err))
+
+	tx, err := db.Begin()
+	check(err)
+	_, err = tx.Exec("update in tx")
+	check(err)
+	result = append(result, fmt.Sprint(tx.Rollback()))
+
+	return strings.Join(result, "\n")
+}
+
+func TestSQL(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	_, fname, _, _ := runtime.Caller(0)
+	defer os.Remove(path.Join(path.Dir(fname), "testdata/golden_sql.json"))
+
+	check(flag.Set("golden", "true"))
+	db := sql.OpenDB(test.SQL{Driver: echoDriver{}}.Connector(t.Error, "golden_sql.json"))
+	recorded := useDatabase(db)
+	check(db.Close())
+
+	check(flag.Set("golden", "false"))
+	db = sql.OpenDB(test.SQL{}.Connector(t.Error, "golden_sql.json"))
+	replayed := useDatabase(db)
+	check(db.Close())
+
+	if recorded != replayed {
+		t.Error("Unexpected replay", recorded, "\n---\n", replayed)
+	}
+	if !strings.Contains(recorded, "select time.Time 2019-09-01 10:00:00 +0000 UTC") {
+		t.Error("Unexpected recording", recorded)
+	}
+}
+
+func TestSQLMismatch(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	_, fname, _, _ := runtime.Caller(0)
+	defer os.Remove(path.Join(path.Dir(fname), "testdata/golden_sql_mismatch.json"))
+
+	check(flag.Set("golden", "true"))
+	db := sql.OpenDB(test.SQL{Driver: echoDriver{}}.Connector(t.Error, "golden_sql_mismatch.json"))
+	_, err := db.Exec("update", 1)
+	check(err)
+	_, err = db.Exec("delete")
+	check(err)
+	check(db.Close())
+
+	var reports []string
+	errorf := func(args ...interface{}) {
+		reports = append(reports, fmt.Sprint(args...))
+	}
+
+	check(flag.Set("golden", "false"))
+	db = sql.OpenDB(test.SQL{}.Connector(errorf, "golden_sql_mismatch.json"))
+	if _, err = db.Exec("update", 2); err == nil {
+		t.Error("Unexpected success")
+	}
+	// the mismatched query is not consumed
+	if _, err = db.Exec("update", 1); err != nil {
+		t.Error("Unexpected failure", err)
+	}
+	check(db.Close())
+
+	if len(reports) != 2 || !strings.Contains(reports[0], "int64") || !strings.HasPrefix(reports[1], "unused query") {
+		t.Error("Unexpected reports", reports)
+	}
+
+	test.SQL{}.Connector(errorf, "non-existent")
+	if len(reports) != 3 {
+		t.Error("Failed to fail")
+	}
+}
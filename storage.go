@@ -0,0 +1,229 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// objectsDir is the name of the content-addressed object directory
+// within testdata.
+const objectsDir = ".objects"
+
+// maxDiffLines is the number of diff lines reported before the rest
+// is summarized.
+const maxDiffLines = 100
+
+var pointerRE = regexp.MustCompile(`^sha256:([0-9a-f]{64}) ([0-9]+)\n?$`)
+
+// readGolden reads the golden file honoring variants, compression and
+// object pointers.
+func readGolden(name string) ([]byte, error) {
+	return loadGolden(goldenPath(name))
+}
+
+// loadGolden reads the golden file at path, decompressing it and
+// resolving object pointers.
+func loadGolden(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := pointerRE.FindSubmatch(data)
+	if m == nil {
+		return decompress(path, data)
+	}
+
+	object := objectPath(path, string(m[1]))
+	if data, err = ioutil.ReadFile(object); err == nil {
+		data, err = decompress(object, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fmt.Sprintf("%x", sha256.Sum256(data)) != string(m[1]) {
+		return nil, fmt.Errorf("object %s does not match its hash", object)
+	}
+	return data, nil
+}
+
+// writeGolden writes the golden file honoring variants and
+// compression.  Data of at least objectSize bytes (if positive) is
+// stored as an object, as is data for existing pointers.
+func writeGolden(name string, data []byte, objectSize int) error {
+	path := goldenPath(name)
+
+	useObject := objectSize > 0 && len(data) >= objectSize
+	if objectSize <= 0 {
+		existing, err := ioutil.ReadFile(path)
+		useObject = err == nil && pointerRE.Match(existing)
+	}
+
+	compressed, err := compress(path, data)
+	if err != nil || !useObject {
+		if err == nil {
+			err = ioutil.WriteFile(path, compressed, 0644)
+		}
+		return err
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+	object := objectPath(path, hash)
+	if _, err := os.Stat(object); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+			return err
+		}
+		if err := writeAtomic(object, compressed); err != nil {
+			return err
+		}
+	}
+	pointer := fmt.Sprintf("sha256:%s %d\n", hash, len(data))
+	return ioutil.WriteFile(path, []byte(pointer), 0644)
+}
+
+// objectPath returns the object for the hash in the object directory
+// of the testdata folder containing the golden file.  Objects of
+// compressed golden files have the same compression suffix.
+func objectPath(golden, hash string) string {
+	dir := filepath.Dir(golden)
+	for d := dir; filepath.Dir(d) != d; d = filepath.Dir(d) {
+		if filepath.Base(d) == "testdata" {
+			dir = d
+			break
+		}
+	}
+	return filepath.Join(dir, objectsDir, hash+compression(golden))
+}
+
+// compression returns the compression suffix of the file name.
+func compression(name string) string {
+	switch ext := filepath.Ext(name); ext {
+	case ".gz", ".zst":
+		return ext
+	}
+	return ""
+}
+
+func compress(name string, data []byte) ([]byte, error) {
+	switch compression(name) {
+	case ".gz":
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		err := w.Close()
+		return buf.Bytes(), err
+	case ".zst":
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+func decompress(name string, data []byte) ([]byte, error) {
+	switch compression(name) {
+	case ".gz":
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	case ".zst":
+		dec, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return dec.DecodeAll(data, nil)
+	}
+	return data, nil
+}
+
+// UnusedObjects returns the objects in the object directories within
+// dir (typically a testdata folder) which are not referenced by any
+// golden file within dir.  The golden files listed in ignore (such as
+// variants about to be removed) are not considered references.
+func UnusedObjects(dir string, ignore ...string) ([]string, error) {
+	ignored := map[string]bool{}
+	for _, name := range ignore {
+		ignored[filepath.Clean(name)] = true
+	}
+
+	used := map[string]bool{}
+	var objects []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || ignored[filepath.Clean(path)] {
+			return err
+		}
+		if filepath.Base(filepath.Dir(path)) == objectsDir {
+			objects = append(objects, path)
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > 128 {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if m := pointerRE.FindSubmatch(data); m != nil {
+			used[objectPath(path, string(m[1]))] = true
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, object := range objects {
+		if !used[object] {
+			result = append(result, object)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// summarizeDiff truncates long diffs, summarizing the number of
+// lines removed and added in the rest.
+func summarizeDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	if len(lines) <= maxDiffLines {
+		return diff
+	}
+
+	// the marker follows any indentation of the diff while the
+	// content of context lines is preceded by a space
+	removed, added := 0, 0
+	for _, line := range lines[maxDiffLines:] {
+		switch line = strings.TrimLeft(line, "\t"); {
+		case strings.HasPrefix(line, "-"):
+			removed++
+		case strings.HasPrefix(line, "+"):
+			added++
+		}
+	}
+	summary := fmt.Sprintf("... %d more lines of diff (%d removed, %d added)", len(lines)-maxDiffLines, removed, added)
+	return strings.Join(append(lines[:maxDiffLines:maxDiffLines], summary), "\n")
+}
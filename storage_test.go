@@ -0,0 +1,161 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func TestCompressedGoldens(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	dir := filepath.Join("testdata", "storage")
+	check(os.MkdirAll(dir, 0755))
+	defer os.RemoveAll(dir)
+
+	magic := map[string][]byte{
+		"out.json.gz":  {0x1f, 0x8b},
+		"out.json.zst": {0x28, 0xb5, 0x2f, 0xfd},
+	}
+	value := map[string]string{"hello": "world"}
+	for name, prefix := range magic {
+		setGoldenFlag(true)
+		test.Artifact(t.Error, "storage/"+name, value)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.HasPrefix(data, prefix) {
+			t.Errorf("Unexpected %s %q %v", name, data, err)
+		}
+
+		setGoldenFlag(false)
+		test.Artifact(t.Error, "storage/"+name, value)
+
+		failed := false
+		test.Artifact(func(args ...interface{}) { failed = true }, "storage/"+name, "boo")
+		if !failed {
+			t.Error("Failed to fail", name)
+		}
+	}
+
+	// variants go before the compression suffix
+	variant := filepath.Join(dir, "out."+runtime.GOARCH+".json.gz")
+	check(ioutil.WriteFile(variant, nil, 0644))
+	setGoldenFlag(true)
+	test.Artifact(t.Error, "storage/out.json.gz", value)
+	redundant, err := test.RedundantVariants(dir)
+	if err != nil || !reflect.DeepEqual(redundant, []string{variant}) {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+}
+
+func TestObjects(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	// the objects are stored in the nearest testdata folder
+	root := filepath.Join("testdata", "storage_objects")
+	dir := filepath.Join(root, "testdata")
+	check(os.MkdirAll(dir, 0755))
+	defer os.RemoveAll(root)
+
+	value := strings.Repeat("large output\n", 100)
+	object := func(s string) string {
+		name := fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+		return filepath.Join(dir, ".objects", name)
+	}
+
+	setGoldenFlag(true)
+	test.File(t.Error, "input.txt", "storage_objects/testdata/a.txt", func(string) string { return value }, test.Objects(1024))
+	test.File(t.Error, "input.txt", "storage_objects/testdata/b.txt", func(string) string { return value }, test.Objects(1024))
+	test.File(t.Error, "input.txt", "storage_objects/testdata/small.txt", strings.ToUpper, test.Objects(1024))
+
+	pointer := fmt.Sprintf("sha256:%x %d\n", sha256.Sum256([]byte(value)), len(value))
+	assertGolden(t, filepath.Join(dir, "a.txt"), pointer)
+	assertGolden(t, filepath.Join(dir, "b.txt"), pointer)
+	assertGolden(t, filepath.Join(dir, "small.txt"), "FIRST LINE\nSECOND LINE\n\n")
+	assertGolden(t, object(value), value)
+
+	setGoldenFlag(false)
+	test.File(t.Error, "input.txt", "storage_objects/testdata/a.txt", func(string) string { return value })
+
+	unused, err := test.UnusedObjects(root)
+	if err != nil || len(unused) != 0 {
+		t.Error("Unexpected unused objects", unused, err)
+	}
+
+	// pointers remain pointers without the option
+	setGoldenFlag(true)
+	changed := value + "changed\n"
+	test.File(t.Error, "input.txt", "storage_objects/testdata/a.txt", func(string) string { return changed })
+	test.File(t.Error, "input.txt", "storage_objects/testdata/b.txt", func(string) string { return changed })
+	assertGolden(t, object(changed), changed)
+
+	unused, err = test.UnusedObjects(root)
+	if err != nil || !reflect.DeepEqual(unused, []string{object(value)}) {
+		t.Error("Unexpected unused objects", unused, err)
+	}
+
+	// ignored golden files do not keep objects in use
+	unused, err = test.UnusedObjects(root, filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"))
+	expected := []string{object(changed), object(value)}
+	sort.Strings(expected)
+	if err != nil || !reflect.DeepEqual(unused, expected) {
+		t.Error("Unexpected unused objects", unused, err)
+	}
+
+	// corrupt objects are reported
+	setGoldenFlag(false)
+	check(ioutil.WriteFile(object(changed), []byte("boo"), 0644))
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+	test.File(errorf, "input.txt", "storage_objects/testdata/a.txt", func(string) string { return changed })
+	if len(failed) != 3 || failed[0] != "error reading" {
+		t.Errorf("Unexpected failure %#v", failed)
+	}
+}
+
+func TestLongDiff(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer os.Remove(filepath.Join("testdata", "storage_long.txt"))
+
+	lines := func(prefix string) string {
+		var buf strings.Builder
+		for kk := 0; kk < 300; kk++ {
+			fmt.Fprintf(&buf, "%s %d\n", prefix, kk)
+		}
+		return buf.String()
+	}
+
+	setGoldenFlag(true)
+	test.File(t.Error, "input.txt", "storage_long.txt", func(string) string { return lines("before") })
+
+	setGoldenFlag(false)
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+	test.File(errorf, "input.txt", "storage_long.txt", func(string) string { return lines("after") })
+	if len(failed) != 2 {
+		t.Fatalf("Unexpected failure %#v", failed)
+	}
+
+	diff := strings.Split(failed[1].(string), "\n")
+	if len(diff) != 101 || diff[100] != "... 504 more lines of diff (250 removed, 251 added)" {
+		t.Error("Unexpected diff", failed[1])
+	}
+}
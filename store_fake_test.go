@@ -0,0 +1,77 @@
+// Code generated by github.com/tvastar/test/cmd/testfake. DO NOT EDIT.
+
+package test_test
+
+import (
+	"github.com/tvastar/test"
+)
+
+// StoreRecorder implements store.Store by forwarding calls to
+// Calls.Target and recording them.
+type StoreRecorder struct {
+	Calls *test.Calls
+}
+
+// Close implements store.Store.
+func (x StoreRecorder) Close() error {
+	results := x.Calls.Call("Close")
+	r0, _ := results[0].(error)
+	return r0
+}
+
+// Get implements store.Store.
+func (x StoreRecorder) Get(key string) (string, error) {
+	results := x.Calls.Call("Get", key)
+	r0, _ := results[0].(string)
+	r1, _ := results[1].(error)
+	return r0, r1
+}
+
+// Keys implements store.Store.
+func (x StoreRecorder) Keys(prefix string, limit ...int) []string {
+	results := x.Calls.Call("Keys", prefix, limit)
+	r0, _ := results[0].([]string)
+	return r0
+}
+
+// Put implements store.Store.
+func (x StoreRecorder) Put(key string, value string) error {
+	results := x.Calls.Call("Put", key, value)
+	r0, _ := results[0].(error)
+	return r0
+}
+
+// StoreFake implements store.Store by replaying calls recorded
+// in the log loaded into Calls.
+type StoreFake struct {
+	Calls *test.Calls
+}
+
+// Close implements store.Store.
+func (x StoreFake) Close() error {
+	var r0 error
+	x.Calls.Replay("Close", []interface{}{}, &r0)
+	return r0
+}
+
+// Get implements store.Store.
+func (x StoreFake) Get(key string) (string, error) {
+	var r0 string
+	var r1 error
+	x.Calls.Replay("Get", []interface{}{key}, &r0, &r1)
+	return r0, r1
+}
+
+// Keys implements store.Store.
+func (x StoreFake) Keys(prefix string, limit ...int) []string {
+	var r0 []string
+	x.Calls.Replay("Keys", []interface{}{prefix, limit}, &r0)
+	return r0
+}
+
+// Put implements store.Store.
+func (x StoreFake) Put(key string, value string) error {
+	var r0 error
+	x.Calls.Replay("Put", []interface{}{key, value}, &r0)
+	return r0
+}
@@ -0,0 +1,244 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Stream implements comparing a sequence of records against a JSON
+// Lines golden file with one JSON encoded record per line.
+//
+// The source is a slice, a channel or an iterator function of the
+// form func() (T, bool) (returning false at the end) or
+// func(yield func(T) bool).  Channels are read until they are closed
+// or Timeout elapses.
+//
+// Records are compared structurally after aligning the two sequences
+// so that an inserted or removed record is reported as such rather
+// than as a change of every following record.
+//
+// Example Usage:
+//
+//    test.Stream{Timeout: time.Second}.Check(t.Error, "events.jsonl", events)
+//
+type Stream struct {
+	// Timeout is the maximum time to wait for a channel to be
+	// closed.  It defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// Check compares the records of source against the golden file
+// (relative to the testdata/ folder of the caller).
+//
+// If the tests are run with -golden flag, the records are not
+// compared but instead saved to the golden file.
+func (s Stream) Check(errorf Errorf, goldenFile string, source interface{}) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+
+	records, err := s.records(source)
+	if err != nil {
+		errorf(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			errorf("Could not marshal value", err)
+			return
+		}
+	}
+
+	if *goldenFlag {
+		if err := ioutil.WriteFile(goldenFile, buf.Bytes(), 0644); err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+
+	expected, err := decodeLines(data)
+	if err != nil {
+		errorf("could not unmarshal", goldenFile, err)
+		return
+	}
+	actual, err := decodeLines(buf.Bytes())
+	if err != nil {
+		errorf("could not unmarshal", err)
+		return
+	}
+
+	if diff := recordDiff(expected, actual); diff != "" {
+		errorf("unexpected output", diff)
+	}
+}
+
+// records collects the values of the slice, channel or iterator.
+func (s Stream) records(source interface{}) ([]interface{}, error) {
+	var result []interface{}
+	v := reflect.ValueOf(source)
+
+	switch {
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		for kk := 0; kk < v.Len(); kk++ {
+			result = append(result, v.Index(kk).Interface())
+		}
+	case v.Kind() == reflect.Chan:
+		if v.Type().ChanDir()&reflect.RecvDir == 0 {
+			return nil, fmt.Errorf("unsupported send-only channel %T", source)
+		}
+		timeout := s.Timeout
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(timeout))},
+		}
+		for {
+			chosen, r, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return nil, fmt.Errorf("stream not closed within %v", timeout)
+			}
+			if !ok {
+				break
+			}
+			result = append(result, r.Interface())
+		}
+	case v.Kind() == reflect.Func && v.Type().NumIn() == 0 && v.Type().NumOut() == 2 && v.Type().Out(1).Kind() == reflect.Bool:
+		for {
+			out := v.Call(nil)
+			if !out[1].Bool() {
+				break
+			}
+			result = append(result, out[0].Interface())
+		}
+	case v.Kind() == reflect.Func && v.Type().NumIn() == 1 && v.Type().In(0).Kind() == reflect.Func:
+		yield := v.Type().In(0)
+		if yield.NumIn() != 1 || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+			return nil, errors.New("unsupported iterator " + v.Type().String())
+		}
+		v.Call([]reflect.Value{reflect.MakeFunc(yield, func(in []reflect.Value) []reflect.Value {
+			result = append(result, in[0].Interface())
+			return []reflect.Value{reflect.ValueOf(true)}
+		})})
+	default:
+		return nil, fmt.Errorf("unsupported stream source %T", source)
+	}
+	return result, nil
+}
+
+func decodeLines(data []byte) ([]interface{}, error) {
+	var result []interface{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// maxRecordDiff is the largest table of longest common subsequence
+// lengths built by recordDiff.
+const maxRecordDiff = 1 << 20
+
+// recordDiff aligns the records via their longest common subsequence
+// and describes the removed (-), inserted (+) and changed (~) records
+// along with their line numbers.  Long streams fall back to a diff of
+// the JSON encoded records.
+func recordDiff(expected, actual []interface{}) string {
+	n, m := len(expected), len(actual)
+	if (n+1)*(m+1) > maxRecordDiff {
+		return linediff(jsonLines(expected), jsonLines(actual))
+	}
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case cmp.Equal(expected[i], actual[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	var removed, inserted []int
+	flush := func() {
+		for len(removed) > 0 && len(inserted) > 0 {
+			i, j := removed[0], inserted[0]
+			diff := cmp.Diff(expected[i], actual[j])
+			lines = append(lines, fmt.Sprintf("~%d: %s", i+1, strings.TrimSpace(diff)))
+			removed, inserted = removed[1:], inserted[1:]
+		}
+		for _, i := range removed {
+			lines = append(lines, fmt.Sprintf("-%d: %s", i+1, compactJSON(expected[i])))
+		}
+		for _, j := range inserted {
+			lines = append(lines, fmt.Sprintf("+%d: %s", j+1, compactJSON(actual[j])))
+		}
+		removed, inserted = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && cmp.Equal(expected[i], actual[j]):
+			flush()
+			i, j = i+1, j+1
+		case j == m || i < n && lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, i)
+			i++
+		default:
+			inserted = append(inserted, j)
+			j++
+		}
+	}
+	flush()
+	return strings.Join(lines, "\n")
+}
+
+func jsonLines(records []interface{}) string {
+	lines := make([]string, len(records))
+	for idx, r := range records {
+		lines[idx] = compactJSON(r)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func compactJSON(v interface{}) string {
+	bytes, _ := json.Marshal(v)
+	return string(bytes)
+}
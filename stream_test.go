@@ -0,0 +1,126 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tvastar/test"
+)
+
+type event struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+}
+
+func events() []event {
+	return []event{{"start", 1}, {"progress", 2}, {"progress", 3}, {"done", 4}}
+}
+
+func TestStream(t *testing.T) {
+	test.Stream{}.Check(t.Error, "stream_events.jsonl", events())
+}
+
+func TestStreamSources(t *testing.T) {
+	ch := make(chan event, 4)
+	for _, e := range events() {
+		ch <- e
+	}
+	close(ch)
+	test.Stream{}.Check(t.Error, "stream_events.jsonl", ch)
+
+	remaining := events()
+	next := func() (event, bool) {
+		if len(remaining) == 0 {
+			return event{}, false
+		}
+		e := remaining[0]
+		remaining = remaining[1:]
+		return e, true
+	}
+	test.Stream{}.Check(t.Error, "stream_events.jsonl", next)
+
+	seq := func(yield func(event) bool) {
+		for _, e := range events() {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+	test.Stream{}.Check(t.Error, "stream_events.jsonl", seq)
+}
+
+func TestStreamMismatch(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+
+	e := events()
+	e = append(e[:2], append([]event{{"retry", 9}}, e[2:]...)...)
+	e[4].ID = 5
+	e = e[1:]
+	test.Stream{}.Check(errorf, "stream_events.jsonl", e)
+
+	if len(failed) != 2 || failed[0] != "unexpected output" {
+		t.Fatalf("Unexpected failure %#v", failed)
+	}
+	lines := strings.Split(failed[1].(string), "\n")
+	if len(lines) < 3 || lines[0] != `-1: {"id":1,"type":"start"}` || lines[1] != `+2: {"id":9,"type":"retry"}` || !strings.HasPrefix(lines[2], "~4: ") {
+		t.Errorf("Unexpected diff %#v", lines)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.Stream{Timeout: time.Millisecond}.Check(errorf, "stream_events.jsonl", make(chan event))
+	test.Stream{}.Check(errorf, "stream_events.jsonl", 42)
+	test.Stream{}.Check(errorf, "stream_events.jsonl", func(yield func(int)) {})
+	test.Stream{}.Check(errorf, "stream_events.jsonl", []interface{}{func() {}})
+	test.Stream{}.Check(errorf, "non-existent.jsonl", events())
+	test.Stream{}.Check(errorf, "input.txt", events())
+	test.Stream{}.Check(errorf, "stream_events.jsonl", make(chan<- event))
+
+	if len(failed) != 7 || failed[3] != "Could not marshal value" || failed[4] != "error reading" || failed[5] != "could not unmarshal" {
+		t.Error("Unexpected failures", failed)
+	}
+	if err, ok := failed[6].(error); !ok || !strings.Contains(err.Error(), "send-only") {
+		t.Error("Unexpected failure", failed[6])
+	}
+}
+
+func TestStreamLong(t *testing.T) {
+	defer restoreGoldenFlag()()
+	defer os.Remove("testdata/stream_long.jsonl")
+
+	records := make([]int, 2000)
+	for idx := range records {
+		records[idx] = idx
+	}
+	setGoldenFlag(true)
+	test.Stream{}.Check(t.Error, "stream_long.jsonl", records)
+
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+	records[1000] = -1
+	setGoldenFlag(false)
+	test.Stream{}.Check(errorf, "stream_long.jsonl", records)
+
+	if len(failed) != 2 || failed[0] != "unexpected output" {
+		t.Fatalf("Unexpected failure %#v", failed)
+	}
+	if diff := failed[1].(string); !strings.Contains(diff, `"1000"`) || !strings.Contains(diff, `"-1"`) || strings.Contains(diff, "~") {
+		t.Error("Unexpected diff", diff)
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Table implements comparing tabular (CSV or TSV) output against
+// golden files.
+//
+// The first record of the data is the header.  Rows are matched by
+// the values of the Keys columns (or by position if there are no
+// Keys) and compared column by column.  Unordered tables without
+// Keys are sorted before being compared by position.  Mismatches are
+// reported as a table of row key, column, expected and actual values.
+//
+// Example Usage:
+//
+//    test.Table{Keys: []string{"id"}, Tolerance: map[string]float64{"price": 0.01}}.
+//        Check(t.Error, "report.csv", report)
+//
+type Table struct {
+	// Keys are the names of the columns which identify a row.
+	Keys []string
+
+	// Unordered compares the rows without regard to their order.
+	Unordered bool
+
+	// Tolerance is the maximum absolute difference allowed
+	// between numeric values of a column.
+	Tolerance map[string]float64
+
+	// Comma is the field delimiter.  It defaults to a tab for
+	// golden files with a .tsv extension and to a comma otherwise.
+	Comma rune
+}
+
+// Check compares the data against the golden file (relative to the
+// testdata/ folder of the caller).
+//
+// If the tests are run with -golden flag, the data is not compared
+// but instead saved to the golden file.
+func (t Table) Check(errorf Errorf, goldenFile, data string) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+	if t.Comma == 0 {
+		t.Comma = ','
+		if filepath.Ext(goldenFile) == ".tsv" {
+			t.Comma = '\t'
+		}
+	}
+
+	actual, err := t.parse(data)
+	if err != nil {
+		errorf("could not parse output", err)
+		return
+	}
+
+	if *goldenFlag {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Comma = t.Comma
+		if err = w.WriteAll(actual); err == nil {
+			err = ioutil.WriteFile(goldenFile, buf.Bytes(), 0644)
+		}
+		if err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	expected, err := t.parse(string(golden))
+	if err != nil {
+		errorf("could not parse golden file", goldenFile, err)
+		return
+	}
+
+	if len(expected) == 0 || len(actual) == 0 {
+		if len(expected) != len(actual) {
+			errorf("unexpected output", linediff(string(golden), data))
+		}
+		return
+	}
+
+	if !equalRecords(expected[0], actual[0]) {
+		errorf("unexpected columns", linediff(strings.Join(expected[0], "\n"), strings.Join(actual[0], "\n")))
+		return
+	}
+
+	mismatches, err := t.compare(expected[0], expected[1:], actual[1:])
+	if err != nil {
+		errorf("could not compare", err)
+	} else if len(mismatches) > 0 {
+		errorf("unexpected output", formatMismatches(mismatches))
+	}
+}
+
+func (t Table) parse(data string) ([][]string, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = t.Comma
+	return r.ReadAll()
+}
+
+// compare matches the expected and actual rows and returns the
+// mismatches as (row key, column, expected, actual) tuples.
+func (t Table) compare(header []string, expected, actual [][]string) ([][4]string, error) {
+	keyOf, err := t.keyFunc(header)
+	if err != nil {
+		return nil, err
+	}
+	if t.Unordered && len(t.Keys) == 0 {
+		sortRecords(expected)
+		sortRecords(actual)
+	}
+
+	index := map[string][]string{}
+	var order []string
+	for idx, row := range actual {
+		key := keyOf(idx, row)
+		if _, ok := index[key]; ok {
+			return nil, fmt.Errorf("duplicate row key %s", key)
+		}
+		index[key] = row
+		order = append(order, key)
+	}
+
+	var result [][4]string
+	var expectedOrder []string
+	for idx, row := range expected {
+		key := keyOf(idx, row)
+		expectedOrder = append(expectedOrder, key)
+		other, ok := index[key]
+		if !ok {
+			result = append(result, [4]string{key, "*", "present", "missing"})
+			continue
+		}
+		delete(index, key)
+
+		for col, name := range header {
+			if col >= len(row) || col >= len(other) || !t.equal(name, row[col], other[col]) {
+				result = append(result, [4]string{key, name, field(row, col), field(other, col)})
+			}
+		}
+	}
+
+	var rest []string
+	for _, key := range order {
+		if _, ok := index[key]; ok {
+			result = append(result, [4]string{key, "*", "missing", "present"})
+		} else {
+			rest = append(rest, key)
+		}
+	}
+
+	if !t.Unordered && len(t.Keys) > 0 && len(result) == 0 && !equalRecords(expectedOrder, rest) {
+		result = append(result, [4]string{"*", "*", "ordered", "reordered"})
+	}
+	return result, nil
+}
+
+// keyFunc returns the function which computes the key of a row.
+func (t Table) keyFunc(header []string) (func(idx int, row []string) string, error) {
+	if len(t.Keys) == 0 {
+		return func(idx int, row []string) string {
+			return "#" + strconv.Itoa(idx+1)
+		}, nil
+	}
+
+	cols := make([]int, len(t.Keys))
+	for kk, name := range t.Keys {
+		cols[kk] = -1
+		for col, h := range header {
+			if h == name {
+				cols[kk] = col
+			}
+		}
+		if cols[kk] < 0 {
+			return nil, fmt.Errorf("unknown key column %s", name)
+		}
+	}
+
+	return func(idx int, row []string) string {
+		parts := make([]string, len(cols))
+		for kk, col := range cols {
+			parts[kk] = t.Keys[kk] + "=" + field(row, col)
+		}
+		return strings.Join(parts, ",")
+	}, nil
+}
+
+func (t Table) equal(column, expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	tolerance, ok := t.Tolerance[column]
+	if !ok {
+		return false
+	}
+	f1, err1 := strconv.ParseFloat(strings.TrimSpace(expected), 64)
+	f2, err2 := strconv.ParseFloat(strings.TrimSpace(actual), 64)
+	return err1 == nil && err2 == nil && math.Abs(f1-f2) <= tolerance
+}
+
+func field(row []string, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return ""
+}
+
+func equalRecords(r1, r2 []string) bool {
+	if len(r1) != len(r2) {
+		return false
+	}
+	for idx := range r1 {
+		if r1[idx] != r2[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortRecords(records [][]string) {
+	sort.SliceStable(records, func(i, j int) bool {
+		r1, r2 := records[i], records[j]
+		for idx := 0; idx < len(r1) && idx < len(r2); idx++ {
+			if r1[idx] != r2[idx] {
+				return r1[idx] < r2[idx]
+			}
+		}
+		return len(r1) < len(r2)
+	})
+}
+
+func formatMismatches(mismatches [][4]string) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ROW\tCOLUMN\tEXPECTED\tACTUAL")
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%q\t%q\n", m[0], m[1], m[2], m[3])
+	}
+	_ = w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+const report = `id,name,price,qty
+1,apple,1.25,10
+2,"banana, ripe",0.50,12
+3,cherry,4.00,100
+`
+
+func TestTable(t *testing.T) {
+	test.Table{}.Check(t.Error, "table_report.csv", report)
+	test.Table{}.Check(t.Error, "table_report.tsv", "id\tname\n1\tapple pie\n2\t\"quoted\"\n")
+}
+
+func TestTableTolerance(t *testing.T) {
+	reordered := `id,name,price,qty
+3,cherry,4.004,100
+1,apple,1.25,10
+2,"banana, ripe",0.5,12
+`
+	tolerance := map[string]float64{"price": 0.01}
+	test.Table{Keys: []string{"id"}, Unordered: true, Tolerance: tolerance}.Check(t.Error, "table_report.csv", reordered)
+	test.Table{Unordered: true, Tolerance: tolerance}.Check(t.Error, "table_report.csv", strings.Replace(reordered, "4.004", "4.00", 1))
+}
+
+func TestTableMismatch(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+
+	changed := `id,name,price,qty
+1,apple,1.30,10
+3,cherry,4.00,99
+4,date,2.00,1
+`
+	test.Table{Keys: []string{"id"}, Tolerance: map[string]float64{"price": 0.01}}.Check(errorf, "table_report.csv", changed)
+	expected := `ROW   COLUMN  EXPECTED   ACTUAL
+id=1  price   "1.25"     "1.30"
+id=2  *       "present"  "missing"
+id=3  qty     "100"      "99"
+id=4  *       "missing"  "present"
+`
+	if len(failed) != 2 || failed[0] != "unexpected output" || failed[1] != expected {
+		t.Errorf("Unexpected failure %#v", failed)
+	}
+
+	failed = nil
+	reordered := `id,name,price,qty
+2,"banana, ripe",0.50,12
+1,apple,1.25,10
+3,cherry,4.00,100
+`
+	test.Table{Keys: []string{"id"}}.Check(errorf, "table_report.csv", reordered)
+	if len(failed) != 2 || !strings.Contains(failed[1].(string), `"ordered"  "reordered"`) {
+		t.Errorf("Unexpected failure %#v", failed)
+	}
+}
+
+func TestTableErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.Table{}.Check(errorf, "table_report.csv", "a,b\n1")
+	test.Table{}.Check(errorf, "non-existent.csv", report)
+	test.Table{}.Check(errorf, "table_report.csv", "id,name\n1,apple\n")
+	test.Table{}.Check(errorf, "table_report.csv", "")
+	test.Table{Keys: []string{"sku"}}.Check(errorf, "table_report.csv", report)
+	test.Table{Keys: []string{"qty"}}.Check(errorf, "table_report.csv", report+"4,date,1,100\n")
+
+	expected := []interface{}{
+		"could not parse output",
+		"error reading",
+		"unexpected columns",
+		"unexpected output",
+		"could not compare",
+		"could not compare",
+	}
+	if len(failed) != len(expected) {
+		t.Fatal("Unexpected failures", failed)
+	}
+	for idx := range expected {
+		if failed[idx] != expected[idx] {
+			t.Error("Unexpected failure", idx, failed[idx])
+		}
+	}
+}
@@ -0,0 +1,485 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCellDiffs is the maximum number of cell differences reported by
+// Terminal.
+const maxCellDiffs = 20
+
+// Terminal implements comparing the screen rendered by ANSI terminal
+// output against golden files.
+//
+// The output is fed through a small virtual terminal of the provided
+// size (80x24 by default) which supports cursor movement, erasing,
+// scrolling, line wrapping and SGR styles (bold, dim, italic,
+// underline, reverse along with 16, 256 and 24-bit colors).  Other
+// escape sequences are ignored.  As with a terminal in cooked mode,
+// "\n" also returns the cursor to the start of the line.
+//
+// The golden file holds the final screen text with trailing blanks
+// removed, followed by the styled cells:
+//
+//    Build PASSED in 3s
+//    -- styles --
+//    1:7-12 bold fg=2
+//
+// where 1:7-12 denotes columns 7 through 12 of row 1.
+//
+// Example Usage:
+//
+//    test.Terminal{Width: 40, Height: 10}.Check(t.Error, "progress.screen", output)
+//
+type Terminal struct {
+	Width, Height int
+}
+
+// Check renders the output and compares the screen against the golden
+// file (relative to the testdata/ folder of the caller).  Differences
+// are reported cell by cell.
+//
+// If the tests are run with -golden flag, the screen is not compared
+// but instead saved to the golden file.
+func (t Terminal) Check(errorf Errorf, goldenFile string, output []byte) {
+	goldenFile = filepath.Join(callerTestdata(2), goldenFile)
+	if t.Width <= 0 {
+		t.Width = 80
+	}
+	if t.Height <= 0 {
+		t.Height = 24
+	}
+
+	actual := newScreen(t.Width, t.Height)
+	actual.write(output)
+
+	if *goldenFlag {
+		if err := ioutil.WriteFile(goldenFile, []byte(actual.format()), 0644); err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	expected, err := parseScreen(string(data), t.Width, t.Height)
+	if err != nil {
+		errorf("could not parse golden file", goldenFile, err)
+		return
+	}
+
+	var diffs []string
+	for row := range expected.cells {
+		for col, c := range expected.cells[row] {
+			if other := actual.cells[row][col]; other != c {
+				diffs = append(diffs, fmt.Sprintf("%d:%d expected %s, got %s", row+1, col+1, c, other))
+			}
+		}
+	}
+	if len(diffs) > maxCellDiffs {
+		diffs = append(diffs[:maxCellDiffs], fmt.Sprintf("... %d more", len(diffs)-maxCellDiffs))
+	}
+	if len(diffs) > 0 {
+		errorf("unexpected screen", strings.Join(diffs, "\n"))
+	}
+}
+
+// cellStyle is the SGR state of a cell.  Colors are empty for the
+// default color, a palette index or #rrggbb.
+type cellStyle struct {
+	bold, dim, italic, underline, reverse bool
+	fg, bg                                string
+}
+
+func (s cellStyle) String() string {
+	var parts []string
+	for _, attr := range []struct {
+		name string
+		on   bool
+	}{{"bold", s.bold}, {"dim", s.dim}, {"italic", s.italic}, {"underline", s.underline}, {"reverse", s.reverse}} {
+		if attr.on {
+			parts = append(parts, attr.name)
+		}
+	}
+	if s.fg != "" {
+		parts = append(parts, "fg="+s.fg)
+	}
+	if s.bg != "" {
+		parts = append(parts, "bg="+s.bg)
+	}
+	return strings.Join(parts, " ")
+}
+
+type cell struct {
+	r     rune
+	style cellStyle
+}
+
+func (c cell) String() string {
+	if s := c.style.String(); s != "" {
+		return fmt.Sprintf("%q (%s)", c.r, s)
+	}
+	return fmt.Sprintf("%q", c.r)
+}
+
+// screen is a minimal virtual terminal.
+type screen struct {
+	width, height int
+	cells         [][]cell
+	row, col      int
+	style         cellStyle
+	saved         [2]int
+	wrap          bool
+}
+
+func newScreen(width, height int) *screen {
+	s := &screen{width: width, height: height}
+	for kk := 0; kk < height; kk++ {
+		s.cells = append(s.cells, s.blankRow())
+	}
+	return s
+}
+
+func (s *screen) blankRow() []cell {
+	row := make([]cell, s.width)
+	for kk := range row {
+		row[kk].r = ' '
+	}
+	return row
+}
+
+func (s *screen) write(data []byte) {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+
+		switch r {
+		case 0x1b:
+			data = s.escape(data)
+		case '\r':
+			s.col, s.wrap = 0, false
+		case '\n':
+			s.col, s.wrap = 0, false
+			s.lineFeed()
+		case '\b':
+			if s.col > 0 {
+				s.col--
+			}
+			s.wrap = false
+		case '\t':
+			s.col = (s.col/8 + 1) * 8
+			if s.col >= s.width {
+				s.col = s.width - 1
+			}
+		default:
+			if r >= ' ' && r != 0x7f {
+				s.put(r)
+			}
+		}
+	}
+}
+
+func (s *screen) put(r rune) {
+	if s.wrap {
+		s.col, s.wrap = 0, false
+		s.lineFeed()
+	}
+	s.cells[s.row][s.col] = cell{r, s.style}
+	if s.col == s.width-1 {
+		s.wrap = true
+	} else {
+		s.col++
+	}
+}
+
+func (s *screen) lineFeed() {
+	if s.row < s.height-1 {
+		s.row++
+		return
+	}
+	s.cells = append(s.cells[1:], s.blankRow())
+}
+
+// escape handles the escape sequence at the start of data and returns
+// the rest of the data.
+func (s *screen) escape(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	switch data[0] {
+	case '[':
+		end := 1
+		for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+			end++
+		}
+		if end == len(data) {
+			return nil
+		}
+		s.csi(string(data[1:end]), data[end])
+		return data[end+1:]
+	case ']', 'P', '_', '^':
+		// string sequences end with BEL or ST
+		for idx := 1; idx < len(data); idx++ {
+			if data[idx] == 0x07 {
+				return data[idx+1:]
+			}
+			if data[idx] == 0x1b && idx+1 < len(data) && data[idx+1] == '\\' {
+				return data[idx+2:]
+			}
+		}
+		return nil
+	case '7':
+		s.saved = [2]int{s.row, s.col}
+	case '8':
+		s.row, s.col, s.wrap = s.saved[0], s.saved[1], false
+	case '(', ')':
+		if len(data) > 1 {
+			return data[2:]
+		}
+		return nil
+	}
+	return data[1:]
+}
+
+func (s *screen) csi(params string, final byte) {
+	if strings.HasPrefix(params, "?") {
+		return
+	}
+
+	var args []int
+	for _, p := range strings.Split(params, ";") {
+		n, _ := strconv.Atoi(p)
+		args = append(args, n)
+	}
+	arg := func(idx, def int) int {
+		if idx < len(args) && args[idx] > 0 {
+			return args[idx]
+		}
+		return def
+	}
+
+	s.wrap = false
+	switch final {
+	case 'A':
+		s.move(s.row-arg(0, 1), s.col)
+	case 'B':
+		s.move(s.row+arg(0, 1), s.col)
+	case 'C':
+		s.move(s.row, s.col+arg(0, 1))
+	case 'D':
+		s.move(s.row, s.col-arg(0, 1))
+	case 'E':
+		s.move(s.row+arg(0, 1), 0)
+	case 'F':
+		s.move(s.row-arg(0, 1), 0)
+	case 'G':
+		s.move(s.row, arg(0, 1)-1)
+	case 'd':
+		s.move(arg(0, 1)-1, s.col)
+	case 'H', 'f':
+		s.move(arg(0, 1)-1, arg(1, 1)-1)
+	case 'J':
+		s.erase(args[0], true)
+	case 'K':
+		s.erase(args[0], false)
+	case 'm':
+		s.sgr(args)
+	case 's':
+		s.saved = [2]int{s.row, s.col}
+	case 'u':
+		s.row, s.col = s.saved[0], s.saved[1]
+	}
+}
+
+func (s *screen) move(row, col int) {
+	s.row, s.col = clamp(row, s.height), clamp(col, s.width)
+}
+
+func clamp(n, limit int) int {
+	if n < 0 {
+		return 0
+	}
+	if n >= limit {
+		return limit - 1
+	}
+	return n
+}
+
+// erase implements ED (display) and EL (line) with the mode 0 (to
+// end), 1 (to start) or 2 (all).
+func (s *screen) erase(mode int, display bool) {
+	blank := cell{' ', cellStyle{bg: s.style.bg}}
+	for row := range s.cells {
+		if !display && row != s.row {
+			continue
+		}
+		for col := range s.cells[row] {
+			pos := row*s.width + col - s.row*s.width - s.col
+			if mode == 0 && pos >= 0 || mode == 1 && pos <= 0 || mode == 2 {
+				s.cells[row][col] = blank
+			}
+		}
+	}
+}
+
+func (s *screen) sgr(args []int) {
+	for idx := 0; idx < len(args); idx++ {
+		switch n := args[idx]; {
+		case n == 0:
+			s.style = cellStyle{}
+		case n == 1:
+			s.style.bold = true
+		case n == 2:
+			s.style.dim = true
+		case n == 3:
+			s.style.italic = true
+		case n == 4:
+			s.style.underline = true
+		case n == 7:
+			s.style.reverse = true
+		case n == 22:
+			s.style.bold, s.style.dim = false, false
+		case n == 23:
+			s.style.italic = false
+		case n == 24:
+			s.style.underline = false
+		case n == 27:
+			s.style.reverse = false
+		case n >= 30 && n <= 37:
+			s.style.fg = strconv.Itoa(n - 30)
+		case n >= 40 && n <= 47:
+			s.style.bg = strconv.Itoa(n - 40)
+		case n >= 90 && n <= 97:
+			s.style.fg = strconv.Itoa(n - 90 + 8)
+		case n >= 100 && n <= 107:
+			s.style.bg = strconv.Itoa(n - 100 + 8)
+		case n == 39:
+			s.style.fg = ""
+		case n == 49:
+			s.style.bg = ""
+		case n == 38 || n == 48:
+			color := ""
+			if idx+2 < len(args) && args[idx+1] == 5 {
+				color = strconv.Itoa(args[idx+2])
+				idx += 2
+			} else if idx+4 < len(args) && args[idx+1] == 2 {
+				color = fmt.Sprintf("#%02x%02x%02x", args[idx+2], args[idx+3], args[idx+4])
+				idx += 4
+			}
+			if n == 38 {
+				s.style.fg = color
+			} else {
+				s.style.bg = color
+			}
+		}
+	}
+}
+
+// format returns the golden form of the screen.
+func (s *screen) format() string {
+	var lines, styles []string
+	for row, cells := range s.cells {
+		runes := make([]rune, len(cells))
+		for col, c := range cells {
+			runes[col] = c.r
+		}
+		lines = append(lines, strings.TrimRight(string(runes), " "))
+
+		for start := 0; start < len(cells); {
+			end := start
+			for end+1 < len(cells) && cells[end+1].style == cells[start].style {
+				end++
+			}
+			if style := cells[start].style.String(); style != "" {
+				styles = append(styles, fmt.Sprintf("%d:%d-%d %s", row+1, start+1, end+1, style))
+			}
+			start = end + 1
+		}
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	result := ""
+	for _, line := range lines {
+		result += line + "\n"
+	}
+	if len(styles) > 0 {
+		result += "-- styles --\n" + strings.Join(styles, "\n") + "\n"
+	}
+	return result
+}
+
+// parseScreen parses the golden form of a screen.
+func parseScreen(data string, width, height int) (*screen, error) {
+	s := newScreen(width, height)
+	text, styles := data, ""
+	if idx := strings.Index(data, "-- styles --\n"); idx >= 0 && (idx == 0 || data[idx-1] == '\n') {
+		text, styles = data[:idx], data[idx+len("-- styles --\n"):]
+	}
+
+	for row, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		for col, r := range []rune(line) {
+			if row >= height || col >= width {
+				return nil, fmt.Errorf("%d:%d is outside the screen", row+1, col+1)
+			}
+			s.cells[row][col].r = r
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(styles), "\n") {
+		if line == "" {
+			continue
+		}
+		var row, start, end int
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid style %q", line)
+		}
+		if _, err := fmt.Sscanf(fields[0], "%d:%d-%d", &row, &start, &end); err != nil {
+			return nil, fmt.Errorf("invalid style %q: %v", line, err)
+		}
+		if row < 1 || row > height || start < 1 || end > width || start > end {
+			return nil, fmt.Errorf("invalid style %q", line)
+		}
+
+		var style cellStyle
+		for _, attr := range fields[1:] {
+			switch {
+			case attr == "bold":
+				style.bold = true
+			case attr == "dim":
+				style.dim = true
+			case attr == "italic":
+				style.italic = true
+			case attr == "underline":
+				style.underline = true
+			case attr == "reverse":
+				style.reverse = true
+			case strings.HasPrefix(attr, "fg="):
+				style.fg = attr[3:]
+			case strings.HasPrefix(attr, "bg="):
+				style.bg = attr[3:]
+			default:
+				return nil, fmt.Errorf("invalid style %q", line)
+			}
+		}
+		for col := start; col <= end; col++ {
+			s.cells[row-1][col-1].style = style
+		}
+	}
+	return s, nil
+}
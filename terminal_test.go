@@ -0,0 +1,79 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+const progress = "\x1b]0;title\x07Downloading\n" +
+	"[          ] 0%\r[#####     ] 50%\r[##########] 100%\n" +
+	"\x1b[1;32mPASS\x1b[0m ok\ttook 3s\n" +
+	"\x1b[38;5;208mwarn\x1b[39m \x1b[48;2;255;0;0m!\x1b[m\n" +
+	"to be erased\x1b[2K\rabc\x1b[1Dz\x1b[?25l\n" +
+	"wrapping a long line"
+
+func TestTerminal(t *testing.T) {
+	test.Terminal{Width: 20, Height: 6}.Check(t.Error, "terminal_progress.screen", []byte(progress))
+	test.Terminal{}.Check(t.Error, "terminal_cursor.screen", []byte("\x1b[2J\x1b[3;5Hmid\x1b[1;1Htop\x1b7\x1b[5Bdown\x1b8!\x1b[K"))
+}
+
+func TestTerminalScroll(t *testing.T) {
+	test.Terminal{Width: 20, Height: 6}.Check(t.Error, "terminal_progress.screen", []byte("scrolled off\n"+progress))
+}
+
+func TestTerminalMismatch(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = args
+	}
+
+	output := strings.Replace(progress, "PASS", "FAIL", 1)
+	output = strings.Replace(output, "[38;5;208m", "[38;5;209m", 1)
+	test.Terminal{Width: 20, Height: 6}.Check(errorf, "terminal_progress.screen", []byte(output))
+
+	expected := strings.Join([]string{
+		`3:1 expected 'P' (bold fg=2), got 'F' (bold fg=2)`,
+		`3:3 expected 'S' (bold fg=2), got 'I' (bold fg=2)`,
+		`3:4 expected 'S' (bold fg=2), got 'L' (bold fg=2)`,
+		`4:1 expected 'w' (fg=208), got 'w' (fg=209)`,
+		`4:2 expected 'a' (fg=208), got 'a' (fg=209)`,
+		`4:3 expected 'r' (fg=208), got 'r' (fg=209)`,
+		`4:4 expected 'n' (fg=208), got 'n' (fg=209)`,
+	}, "\n")
+	if len(failed) != 2 || failed[0] != "unexpected screen" || failed[1] != expected {
+		t.Errorf("Unexpected failure %#v", failed)
+	}
+}
+
+func TestTerminalErrors(t *testing.T) {
+	var failed []interface{}
+	errorf := func(args ...interface{}) {
+		failed = append(failed, args[0])
+	}
+
+	test.Terminal{}.Check(errorf, "non-existent.screen", nil)
+	test.Terminal{Width: 4}.Check(errorf, "terminal_progress.screen", nil)
+	test.Terminal{}.Check(errorf, "terminal_bad_style.screen", nil)
+	test.Terminal{}.Check(errorf, "terminal_blank_style.screen", nil)
+
+	expected := []interface{}{
+		"error reading",
+		"could not parse golden file",
+		"could not parse golden file",
+		"could not parse golden file",
+	}
+	if len(failed) != len(expected) {
+		t.Fatal("Unexpected failures", failed)
+	}
+	for idx := range expected {
+		if failed[idx] != expected[idx] {
+			t.Error("Unexpected failure", idx, failed[idx])
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Command cat copies stdin to stdout, prints its arguments and the
+// working directory to stderr and exits with the code in $EXIT.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+func main() {
+	if _, err := io.Copy(os.Stdout, os.Stdin); err != nil {
+		panic(err)
+	}
+
+	dir, _ := os.Getwd()
+	fmt.Fprintln(os.Stderr, "args:", os.Args[1:])
+	fmt.Fprintln(os.Stderr, "dir:", dir)
+
+	code, _ := strconv.Atoi(os.Getenv("EXIT"))
+	os.Exit(code)
+}
@@ -0,0 +1,11 @@
+package hello
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hello greets the name.
+func Hello(name string) string {
+	return fmt.Sprint("hello ", strings.ToUpper(name))
+}
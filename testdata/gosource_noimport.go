@@ -0,0 +1,8 @@
+package hello
+
+import "strings"
+
+// Hello greets the name.
+func Hello(name string) string {
+	return fmt.Sprint("hello ", strings.ToUpper(name))
+}
@@ -0,0 +1,8 @@
+package hello
+
+import "strings"
+
+// Hello greets the name.
+func Hello(name string) string {
+	return "hello " + strings.ToUpperCase(name)
+}
@@ -0,0 +1,12 @@
+// Package store defines a sample interface for generating fakes.
+package store
+
+import "io"
+
+// Store is a simple key value store.
+type Store interface {
+	Get(key string) (string, error)
+	Put(key, value string) error
+	Keys(prefix string, limit ...int) []string
+	io.Closer
+}
@@ -0,0 +1,18 @@
+// Command usage prints its usage and fails unless the -o flag is
+// provided.
+package main
+
+import (
+	"flag"
+	"os"
+)
+
+var output = flag.String("o", "", "output file name")
+
+func main() {
+	flag.Parse()
+	if *output == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+}
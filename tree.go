@@ -0,0 +1,192 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/tools/txtar"
+)
+
+// Tree implements testing a directory tree against a golden snapshot.
+//
+// The root is either an fs.FS or the path of a directory.  Relative
+// paths are resolved against the testdata/ folder of the caller while
+// absolute paths, such as those of temporary output directories, are
+// used as is.  The snapshot is a txtar archive (relative to the
+// testdata/ folder of the caller) with one entry per regular file,
+// named by its slash separated path and permission bits:
+//
+//    -- gen/main.go 0644 --
+//    package main
+//    -- gen/logo.png 0644 binary --
+//    sha256:5d41402abc4b2a76b9719d911017c592 1024
+//
+// Text files are stored inline.  Binary files (and text files which
+// would not survive the archive format, such as those with a missing
+// final newline) are stored as their SHA-256 hash and size.
+//
+// Added, removed and modified files are reported via errorf, with a
+// line diff for text files.
+//
+// If the tests are run with -golden flag, the snapshot is not
+// compared but instead atomically rewritten.
+//
+// Example Usage:
+//
+//    test.Tree(t.Error, os.DirFS(outputDir), "generated.txtar")
+//
+func Tree(errorf Errorf, root interface{}, goldenFile string) {
+	testdata := callerTestdata(2)
+	goldenFile = filepath.Join(testdata, goldenFile)
+
+	fsys, ok := root.(fs.FS)
+	if dir, isPath := root.(string); isPath {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(testdata, dir)
+		}
+		fsys, ok = os.DirFS(dir), true
+	}
+	if !ok {
+		errorf("unsupported tree root", fmt.Sprintf("%T", root))
+		return
+	}
+
+	actual, err := snapshot(fsys)
+	if err != nil {
+		errorf("error reading tree", err)
+		return
+	}
+
+	if *goldenFlag {
+		if err := writeAtomic(goldenFile, txtar.Format(actual)); err != nil {
+			errorf("Could not save golden output", goldenFile, err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		errorf("error reading", goldenFile, err)
+		return
+	}
+	expected := txtar.Parse(data)
+
+	for _, f := range expected.Files {
+		if path := treeEntryPath(f.Name); findTreeEntry(actual, path) == nil {
+			errorf("removed file", path)
+		}
+	}
+
+	for _, f := range actual.Files {
+		path := treeEntryPath(f.Name)
+		other := findTreeEntry(expected, path)
+		switch {
+		case other == nil:
+			errorf("added file", path)
+		case other.Name != f.Name:
+			errorf("modified file", path, other.Name[len(path)+1:]+" => "+f.Name[len(path)+1:])
+		case !bytes.Equal(fixNewline(other.Data), f.Data):
+			errorf("modified file", path, linediff(string(fixNewline(other.Data)), string(f.Data)))
+		}
+	}
+}
+
+// snapshot returns the archive of all regular files in fsys.
+func snapshot(fsys fs.FS) (*txtar.Archive, error) {
+	a := &txtar.Archive{}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%s %04o", path, info.Mode().Perm())
+		if !isTextFile(data) {
+			name += " binary"
+			data = []byte(fmt.Sprintf("sha256:%x %d\n", sha256.Sum256(data), len(data)))
+		}
+		a.Files = append(a.Files, txtar.File{Name: name, Data: data})
+		return nil
+	})
+	return a, err
+}
+
+// isTextFile reports whether data can be stored verbatim in a txtar
+// archive.
+func isTextFile(data []byte) bool {
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		return false
+	}
+	// data with marker lines would be parsed as several files
+	return len(txtar.Parse(data).Files) == 0
+}
+
+// treeEntryPath strips the mode and flags from an archive entry name.
+func treeEntryPath(name string) string {
+	name = strings.TrimSuffix(name, " binary")
+	if idx := strings.LastIndex(name, " "); idx >= 0 {
+		return name[:idx]
+	}
+	return name
+}
+
+func findTreeEntry(a *txtar.Archive, path string) *txtar.File {
+	for kk := range a.Files {
+		if treeEntryPath(a.Files[kk].Name) == path {
+			return &a.Files[kk]
+		}
+	}
+	return nil
+}
+
+func fixNewline(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		return append(append([]byte(nil), data...), '\n')
+	}
+	return data
+}
+
+// writeAtomic writes data to a temporary file in the same directory
+// and renames it to the target so readers never see partial output.
+func writeAtomic(name string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tvastar/test"
+)
+
+func generatedTree() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md":        {Data: []byte("# generated\n"), Mode: 0644},
+		"bin/tool":         {Data: []byte{0x7f, 'E', 'L', 'F', 0}, Mode: 0755},
+		"gen/main.go":      {Data: []byte("package main\n\nfunc main() {}\n"), Mode: 0644},
+		"gen/no_newline":   {Data: []byte("no newline"), Mode: 0644},
+		"gen/nested.txtar": {Data: []byte("-- a.txt --\nhello\n"), Mode: 0644},
+	}
+}
+
+func TestTree(t *testing.T) {
+	test.Tree(t.Error, generatedTree(), "tree_generated.txtar")
+	test.Tree(t.Error, "store", "tree_store.txtar")
+
+	abs, err := filepath.Abs("testdata/store")
+	check(err)
+	test.Tree(t.Error, abs, "tree_store.txtar")
+}
+
+func TestTreeMismatch(t *testing.T) {
+	tree := generatedTree()
+	delete(tree, "README.md")
+	tree["gen/extra.go"] = &fstest.MapFile{Data: []byte("package main\n"), Mode: 0644}
+	tree["gen/main.go"].Data = []byte("package main\n\nfunc main() { run() }\n")
+	tree["bin/tool"].Mode = 0644
+
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0], args[1])
+	}
+	test.Tree(errorf, tree, "tree_generated.txtar")
+
+	expected := []interface{}{
+		"removed file", "README.md",
+		"modified file", "bin/tool",
+		"added file", "gen/extra.go",
+		"modified file", "gen/main.go",
+	}
+	if len(reports) != len(expected) {
+		t.Fatal("Unexpected reports", reports)
+	}
+	for idx := range expected {
+		if reports[idx] != expected[idx] {
+			t.Error("Unexpected report", idx, reports[idx])
+		}
+	}
+}
+
+func TestTreeErrors(t *testing.T) {
+	var reports []interface{}
+	errorf := func(args ...interface{}) {
+		reports = append(reports, args[0])
+	}
+	test.Tree(errorf, 42, "tree_generated.txtar")
+	test.Tree(errorf, "non-existent", "tree_generated.txtar")
+	test.Tree(errorf, "store", "non-existent.txtar")
+
+	expected := []interface{}{"unsupported tree root", "error reading tree", "error reading"}
+	if len(reports) != len(expected) {
+		t.Fatal("Unexpected reports", reports)
+	}
+	for idx := range expected {
+		if reports[idx] != expected[idx] {
+			t.Error("Unexpected report", idx, reports[idx])
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// variantTags returns the tags for the current Go version and
+// platform in the order they are looked up.
+func variantTags() []string {
+	tags := []string{}
+	if v := goVersionRE.FindString(runtime.Version()); v != "" {
+		tags = append(tags, v)
+	}
+	return append(tags, runtime.GOOS+"_"+runtime.GOARCH, runtime.GOARCH, runtime.GOOS)
+}
+
+var goVersionRE = regexp.MustCompile(`go[0-9]+\.[0-9]+`)
+
+// splitExt splits the file name into the parts before and after the
+// extension (which includes the dot).  The extension of compressed
+// files includes the extension before the compression suffix, such
+// as .json.gz.
+func splitExt(name string) (string, string) {
+	ext := filepath.Ext(name)
+	if strings.HasPrefix(filepath.Base(name), ".") && filepath.Base(name) == ext {
+		ext = ""
+	}
+	if ext == ".gz" || ext == ".zst" {
+		rest, inner := splitExt(name[:len(name)-len(ext)])
+		return rest, inner + ext
+	}
+	return name[:len(name)-len(ext)], ext
+}
+
+// goldenPath returns the most specific existing variant of the
+// golden file or the name itself if there are none.
+func goldenPath(name string) string {
+	base, ext := splitExt(name)
+	for _, tag := range variantTags() {
+		variant := base + "." + tag + ext
+		if info, err := os.Stat(variant); err == nil && !info.IsDir() {
+			return variant
+		}
+	}
+	return name
+}
+
+var variantRE = regexp.MustCompile(`^(.*?)\.(go[0-9]+\.[0-9]+|[a-z0-9]+(?:_[a-z0-9]+)?)$`)
+
+// parseVariant returns the default golden file name and the rank of
+// the variant tag of path.  The rank is -1 if path is not a variant.
+// Only tags before an extension are recognized, as a file such as
+// main.js is not a variant of main.
+func parseVariant(path string) (string, int) {
+	rest, ext := splitExt(path)
+	if m := variantRE.FindStringSubmatch(rest); ext != "" && m != nil && variantRank(m[2]) >= 0 {
+		return m[1] + ext, variantRank(m[2])
+	}
+	return "", -1
+}
+
+// variantRank returns the lookup order of the tag (lower is looked up
+// first) or -1 if it is not a variant tag.
+func variantRank(tag string) int {
+	switch {
+	case goVersionRE.FindString(tag) == tag:
+		return 0
+	case strings.Contains(tag, "_"):
+		parts := strings.SplitN(tag, "_", 2)
+		if knownOS[parts[0]] && knownArch[parts[1]] {
+			return 1
+		}
+	case knownArch[tag]:
+		return 2
+	case knownOS[tag]:
+		return 3
+	}
+	return -1
+}
+
+// RedundantVariants returns the golden file variants in dir (and its
+// sub-directories) which are identical to their default golden file.
+//
+// Artifact and File look up variants of a golden file such as out.json
+// in this order before falling back to out.json itself:
+//
+//    out.go1.22.json       (Go version)
+//    out.linux_arm64.json  (GOOS_GOARCH)
+//    out.arm64.json        (GOARCH)
+//    out.linux.json        (GOOS)
+//
+// A variant is only considered redundant if all the variants which
+// would be looked up after it (or at the same position on other
+// platforms) are identical to the default as well, so that removing
+// the returned files does not change the golden file used on any
+// platform.  Variants of golden files without an
+// extension are not reported.
+func RedundantVariants(dir string) ([]string, error) {
+	// variants maps default golden file names to their variants
+	variants := map[string][]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			if d != nil && d.IsDir() && d.Name() == objectsDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if name, rank := parseVariant(path); rank >= 0 {
+			variants[name] = append(variants[name], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for name, paths := range variants {
+		def, err := loadGolden(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// a differing variant keeps the variants at the same or
+		// an earlier lookup position in use
+		differing := -1
+		identical := map[string]bool{}
+		for _, path := range paths {
+			data, err := loadGolden(path)
+			if err != nil {
+				return nil, err
+			}
+			_, rank := parseVariant(path)
+			if bytes.Equal(data, def) {
+				identical[path] = true
+			} else if rank > differing {
+				differing = rank
+			}
+		}
+		for _, path := range paths {
+			if _, rank := parseVariant(path); identical[path] && rank > differing {
+				result = append(result, path)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true,
+	"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+	"js": true, "linux": true, "nacl": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "arm": true, "arm64": true,
+	"loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "ppc64": true, "ppc64le": true, "riscv64": true,
+	"s390x": true, "sparc64": true, "wasm": true,
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2019 rameshvk. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package test_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tvastar/test"
+)
+
+func TestVariants(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	dir := filepath.Join("testdata", "variant")
+	check(os.MkdirAll(dir, 0755))
+	defer os.RemoveAll(dir)
+
+	setGoldenFlag(true)
+	test.Artifact(t.Error, "variant/out.json", "default")
+
+	variant := filepath.Join(dir, "out."+runtime.GOARCH+".json")
+	check(ioutil.WriteFile(variant, nil, 0644))
+	test.Artifact(t.Error, "variant/out.json", "platform")
+
+	setGoldenFlag(false)
+	test.Artifact(t.Error, "variant/out.json", "platform")
+	assertGolden(t, filepath.Join(dir, "out.json"), "\"default\"\n")
+	assertGolden(t, variant, "\"platform\"\n")
+
+	failed := false
+	test.Artifact(func(args ...interface{}) { failed = true }, "variant/out.json", "default")
+	if !failed {
+		t.Error("Failed to fail")
+	}
+
+	redundant, err := test.RedundantVariants(dir)
+	if err != nil || len(redundant) != 0 {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+
+	setGoldenFlag(true)
+	test.Artifact(t.Error, "variant/out.json", "default")
+	redundant, err = test.RedundantVariants(dir)
+	if err != nil || !reflect.DeepEqual(redundant, []string{variant}) {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+
+	// a differing less specific variant keeps the variant in use
+	check(ioutil.WriteFile(filepath.Join(dir, "out."+runtime.GOOS+".json"), []byte("\"os\"\n"), 0644))
+	redundant, err = test.RedundantVariants(dir)
+	if err != nil || len(redundant) != 0 {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+
+	// variants at the same lookup position are kept alike
+	check(os.Remove(filepath.Join(dir, "out."+runtime.GOOS+".json")))
+	check(ioutil.WriteFile(filepath.Join(dir, "out.darwin.json"), []byte("\"default\"\n"), 0644))
+	check(ioutil.WriteFile(filepath.Join(dir, "out.linux.json"), []byte("\"os\"\n"), 0644))
+	redundant, err = test.RedundantVariants(dir)
+	if err != nil || len(redundant) != 0 {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+
+	// extensions which look like tags are not variants
+	for _, name := range []string{"main", "main.js", "lib", "lib.wasm"} {
+		check(ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	redundant, err = test.RedundantVariants(dir)
+	if err != nil || len(redundant) != 0 {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+}
+
+func TestFileVariants(t *testing.T) {
+	defer restoreGoldenFlag()()
+
+	dir := filepath.Join("testdata", "variant_file")
+	check(os.MkdirAll(dir, 0755))
+	defer os.RemoveAll(dir)
+
+	version := regexp.MustCompile(`go[0-9]+\.[0-9]+`).FindString(runtime.Version())
+	output := filepath.Join(dir, "output.txt")
+	variant := filepath.Join(dir, "output."+version+".txt")
+	check(ioutil.WriteFile(output, []byte("first line\nsecond line\n\n"), 0644))
+	check(ioutil.WriteFile(variant, []byte("FIRST LINE\nSECOND LINE\n\n"), 0644))
+
+	test.File(t.Error, "input.txt", "variant_file/output.txt", strings.ToUpper)
+
+	setGoldenFlag(true)
+	test.File(t.Error, "input.txt", "variant_file/output.txt", strings.ToLower)
+	assertGolden(t, variant, "first line\nsecond line\n\n")
+
+	redundant, err := test.RedundantVariants(dir)
+	if err != nil || !reflect.DeepEqual(redundant, []string{variant}) {
+		t.Error("Unexpected redundant variants", redundant, err)
+	}
+}
+
+func assertGolden(t *testing.T, name, expected string) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil || string(data) != expected {
+		t.Errorf("Unexpected %s: %q %v", name, data, err)
+	}
+}